@@ -0,0 +1,73 @@
+package ctx
+
+import (
+	"fmt"
+
+	"github.com/integration-system/isp-lib/v2/structure"
+)
+
+const (
+	gateBaseHost   = "isp-gate"
+	gateModuleName = "gate"
+	GateHttpPort   = "9090"
+	gateGrpcPort   = "9091"
+
+	systemServiceBaseHost   = "isp-system-service"
+	systemServiceModuleName = "system"
+	SystemServiceGrpcPort   = "9000"
+	systemServiceSchema     = "system_service"
+)
+
+type GateLocalConfiguration struct {
+	DefaultLocalConfiguration
+	HttpOuterAddress structure.AddressConfiguration
+	HttpInnerAddress structure.AddressConfiguration
+}
+
+// gate proxies http requests to modules and checks application tokens stored in redis
+type GateRemoteConfiguration struct {
+	Redis structure.RedisConfiguration
+}
+
+// system service stores applications and tokens in postgres and publishes them to redis
+type SystemServiceRemoteConfiguration struct {
+	Database structure.DBConfiguration
+	Redis    structure.RedisConfiguration
+}
+
+// produce local configuration for gate instance
+func (ctx *TestContext) GetGateLocalConfig() GateLocalConfiguration {
+	containerName := fmt.Sprintf("%s-%s", gateBaseHost, ctx.buildName())
+	cfg := ctx.GetModuleLocalConfig(gateGrpcPort, gateModuleName)
+	cfg.GrpcOuterAddress.IP = containerName
+	return GateLocalConfiguration{
+		DefaultLocalConfiguration: cfg,
+		HttpOuterAddress:          structure.AddressConfiguration{IP: containerName, Port: GateHttpPort},
+		HttpInnerAddress:          structure.AddressConfiguration{IP: bindAddress, Port: GateHttpPort},
+	}
+}
+
+// produce remote configuration for gate, redis is expected to be run by RunRedisContainer
+func (ctx *TestContext) GetGateRemoteConfig() GateRemoteConfiguration {
+	return GateRemoteConfiguration{
+		Redis: ctx.GetRedisConfiguration(),
+	}
+}
+
+// produce local configuration for system service instance
+func (ctx *TestContext) GetSystemServiceLocalConfig() DefaultLocalConfiguration {
+	cfg := ctx.GetModuleLocalConfig(SystemServiceGrpcPort, systemServiceModuleName)
+	cfg.GrpcOuterAddress.IP = fmt.Sprintf("%s-%s", systemServiceBaseHost, ctx.buildName())
+	return cfg
+}
+
+// produce remote configuration for system service, postgres and redis are expected to be run
+// by RunPGContainer and RunRedisContainer
+func (ctx *TestContext) GetSystemServiceRemoteConfig() SystemServiceRemoteConfiguration {
+	dbCfg := ctx.GetDBConfiguration()
+	dbCfg.Schema = systemServiceSchema
+	return SystemServiceRemoteConfiguration{
+		Database: dbCfg,
+		Redis:    ctx.GetRedisConfiguration(),
+	}
+}
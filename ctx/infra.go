@@ -0,0 +1,193 @@
+package ctx
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPGImage         = "docker.io/library/postgres:alpine"
+	DefaultRabbitImage     = "docker.io/library/rabbitmq:alpine"
+	DefaultElasticImage    = "docker.io/library/elasticsearch:6.8.4"
+	DefaultRedisImage      = "docker.io/library/redis:alpine"
+	DefaultKafkaImage      = "docker.io/bitnami/kafka:3.4"
+	DefaultMinioImage      = "docker.io/minio/minio:latest"
+	DefaultMongoImage      = "docker.io/library/mongo:4.4"
+	DefaultClickHouseImage = "docker.io/clickhouse/clickhouse-server:22.8"
+)
+
+var matrixFlag = flag.String("matrix", "",
+	"comma separated names of matrix combinations to run, all combinations run by default")
+
+// images and credentials of infrastructure containers, empty values are replaced by defaults
+type InfraConfiguration struct {
+	Postgres struct {
+		Image    string
+		Database string
+		Password string
+	}
+	Rabbit struct {
+		Image    string
+		Username string
+		Password string
+	}
+	Elastic struct {
+		Image string
+	}
+	Redis struct {
+		Image    string
+		Password string
+	}
+	Kafka struct {
+		Image string
+	}
+	ObjectStorage struct {
+		Image string
+	}
+	Mongo struct {
+		Image    string
+		Database string
+		Username string
+		Password string
+	}
+	ClickHouse struct {
+		Image    string
+		Database string
+		Username string
+		Password string
+	}
+}
+
+// MatrixCombination overrides infrastructure configuration for one run of the whole suite
+type MatrixCombination struct {
+	Name  string
+	Infra InfraConfiguration
+}
+
+// returns infra with empty values replaced by values from base
+func (infra InfraConfiguration) merge(base InfraConfiguration) InfraConfiguration {
+	result := base
+	setIfNotEmpty(&result.Postgres.Image, infra.Postgres.Image)
+	setIfNotEmpty(&result.Postgres.Database, infra.Postgres.Database)
+	setIfNotEmpty(&result.Postgres.Password, infra.Postgres.Password)
+	setIfNotEmpty(&result.Rabbit.Image, infra.Rabbit.Image)
+	setIfNotEmpty(&result.Rabbit.Username, infra.Rabbit.Username)
+	setIfNotEmpty(&result.Rabbit.Password, infra.Rabbit.Password)
+	setIfNotEmpty(&result.Elastic.Image, infra.Elastic.Image)
+	setIfNotEmpty(&result.Redis.Image, infra.Redis.Image)
+	setIfNotEmpty(&result.Redis.Password, infra.Redis.Password)
+	setIfNotEmpty(&result.Kafka.Image, infra.Kafka.Image)
+	setIfNotEmpty(&result.ObjectStorage.Image, infra.ObjectStorage.Image)
+	setIfNotEmpty(&result.Mongo.Image, infra.Mongo.Image)
+	setIfNotEmpty(&result.Mongo.Database, infra.Mongo.Database)
+	setIfNotEmpty(&result.Mongo.Username, infra.Mongo.Username)
+	setIfNotEmpty(&result.Mongo.Password, infra.Mongo.Password)
+	setIfNotEmpty(&result.ClickHouse.Image, infra.ClickHouse.Image)
+	setIfNotEmpty(&result.ClickHouse.Database, infra.ClickHouse.Database)
+	setIfNotEmpty(&result.ClickHouse.Username, infra.ClickHouse.Username)
+	setIfNotEmpty(&result.ClickHouse.Password, infra.ClickHouse.Password)
+	return result
+}
+
+func defaultInfraConfiguration() InfraConfiguration {
+	infra := InfraConfiguration{}
+	infra.Postgres.Image = DefaultPGImage
+	infra.Postgres.Database = PgSqlDbName
+	infra.Postgres.Password = PgSqlPassword
+	infra.Rabbit.Image = DefaultRabbitImage
+	infra.Rabbit.Username = rabbitUsername
+	infra.Rabbit.Password = rabbitPassword
+	infra.Elastic.Image = DefaultElasticImage
+	infra.Redis.Image = DefaultRedisImage
+	infra.Kafka.Image = DefaultKafkaImage
+	infra.ObjectStorage.Image = DefaultMinioImage
+	infra.Mongo.Image = DefaultMongoImage
+	infra.Mongo.Database = mongoDbName
+	infra.Mongo.Username = mongoUsername
+	infra.Mongo.Password = mongoPassword
+	infra.ClickHouse.Image = DefaultClickHouseImage
+	infra.ClickHouse.Database = clickHouseDbName
+	infra.ClickHouse.Username = clickHouseUsername
+	infra.ClickHouse.Password = clickHousePassword
+	return infra
+}
+
+func setIfNotEmpty(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// WithMatrix sets combinations to run the whole suite against
+// overrides BaseTestConfiguration.Matrix from config file
+func (r *IntegrationTestRunner) WithMatrix(combinations ...MatrixCombination) *IntegrationTestRunner {
+	r.matrix = combinations
+	return r
+}
+
+// returns combinations selected by -matrix flag, all combinations if flag is empty
+// fails if flag contains names of unknown combinations
+func (r *IntegrationTestRunner) matrixCombinations() ([]MatrixCombination, error) {
+	combinations := r.matrix
+	if len(combinations) == 0 {
+		combinations = r.ctx.baseCfg.Matrix
+	}
+	if *matrixFlag == "" {
+		return combinations, nil
+	}
+	known := make(map[string]bool, len(combinations))
+	names := make([]string, 0, len(combinations))
+	for _, c := range combinations {
+		known[c.Name] = true
+		names = append(names, c.Name)
+	}
+	selected := make(map[string]bool)
+	unknown := make([]string, 0)
+	for _, name := range strings.Split(*matrixFlag, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+		selected[name] = true
+	}
+	if len(unknown) > 0 {
+		return nil, errors.Errorf("unknown matrix combinations: %s, available: %s",
+			strings.Join(unknown, ", "), strings.Join(names, ", "))
+	}
+	result := make([]MatrixCombination, 0, len(combinations))
+	for _, c := range combinations {
+		if selected[c.Name] {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// run the whole suite for each combination, returns first non-zero exit code
+func (r *IntegrationTestRunner) runMatrix(combinations []MatrixCombination) int {
+	codes := make([]int, len(combinations))
+	for i, c := range combinations {
+		fmt.Printf("=== MATRIX %s\n", c.Name)
+		codes[i] = r.runner(r.ctx.withMatrixCombination(c), r.m.Run)
+	}
+
+	code := 0
+	fmt.Println("--- MATRIX RESULTS")
+	for i, c := range combinations {
+		status := "PASS"
+		if codes[i] != 0 {
+			status = "FAIL"
+			if code == 0 {
+				code = codes[i]
+			}
+		}
+		fmt.Printf("%s\t%s\n", status, c.Name)
+	}
+	return code
+}
@@ -0,0 +1,22 @@
+package ctx
+
+import (
+	"flag"
+	"path/filepath"
+)
+
+// cassettes are stored relative to the package directory, as go test runs tests there
+const cassettesDir = "testdata/cassettes"
+
+var replayFlag = flag.Bool("replay", false,
+	"replay dependency traffic recorded in cassettes instead of recording it, dependencies are not required to run")
+
+// returns true if suite runs with -replay flag
+func (ctx *TestContext) ReplayMode() bool {
+	return *replayFlag
+}
+
+// returns path of cassette file with recorded traffic of dependency
+func (ctx *TestContext) GetCassettePath(name string) string {
+	return filepath.Join(cassettesDir, name+".json")
+}
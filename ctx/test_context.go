@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +25,7 @@ const (
 	configServiceBaseHost = "isp-config-service"
 	configServiceHttpPort = "9001"
 	configServiceGrpcPort = "9002"
+	configServiceRaftPort = "9003"
 	configServiceSchema   = "config_service"
 	configModuleName      = "config"
 
@@ -41,6 +44,28 @@ const (
 	elasticBaseHost = "isp-elastic"
 	ElasticPort     = "9200"
 
+	redisBaseHost = "isp-redis"
+	redisPort     = "6379"
+
+	kafkaBaseHost = "isp-kafka"
+	KafkaPort     = "9092"
+
+	mongoBaseHost = "isp-mongo"
+	mongoPort     = "27017"
+	mongoDbName   = "isp_test"
+	mongoUsername = "isp_test"
+	mongoPassword = "123321"
+
+	clickHouseBaseHost = "isp-clickhouse"
+	clickHousePort     = "9000"
+	clickHouseDbName   = "isp_test"
+	clickHouseUsername = "isp_test"
+	clickHousePassword = "123321"
+
+	objectStorageBaseHost = "isp-s3"
+	ObjectStoragePort     = "9000"
+	objectStorageRegion   = "us-east-1"
+
 	dockerNetwork = "isp-test-network"
 
 	TestConfigEnvPrefix = "ISP_TEST"
@@ -54,6 +79,17 @@ type ConfigServiceLocalConfiguration struct {
 		Rest structure.AddressConfiguration
 		Grpc structure.AddressConfiguration
 	}
+	Cluster ConfigServiceClusterConfiguration
+}
+
+// raft settings of config-service node, zero values keep image defaults
+type ConfigServiceClusterConfiguration struct {
+	InMemory         bool
+	BootstrapCluster bool
+	OuterAddress     string
+	// comma separated raft addresses of all cluster nodes
+	Peers                 string
+	ConnectTimeoutSeconds int
 }
 
 type DefaultLocalConfiguration struct {
@@ -63,6 +99,11 @@ type DefaultLocalConfiguration struct {
 	ModuleName           string
 }
 
+// ContainerName returns host name other containers use to reach module
+func (c DefaultLocalConfiguration) ContainerName() string {
+	return c.GrpcOuterAddress.IP
+}
+
 // runner must prepare environment for test, defer all resources closing and finally call runTest
 type Runner func(ctx *TestContext, runTest func() int) int
 
@@ -70,6 +111,7 @@ type IntegrationTestRunner struct {
 	m      *testing.M
 	ctx    *TestContext
 	runner Runner
+	matrix []MatrixCombination
 }
 
 var (
@@ -99,6 +141,15 @@ func (r *IntegrationTestRunner) PrepareAndRun() {
 		return
 	}
 
+	combinations, err := r.matrixCombinations()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(combinations) > 0 {
+		os.Exit(r.runMatrix(combinations))
+	}
+
 	code := r.runner(r.ctx, r.m.Run)
 	os.Exit(code)
 }
@@ -121,7 +172,11 @@ type BaseTestConfiguration struct {
 	Images struct {
 		ConfigService string
 		Module        string
+		Gate          string
+		SystemService string
 	}
+	Infra  InfraConfiguration
+	Matrix []MatrixCombination
 }
 
 func (tc *BaseTestConfiguration) GetBaseConfiguration() BaseTestConfiguration {
@@ -130,8 +185,12 @@ func (tc *BaseTestConfiguration) GetBaseConfiguration() BaseTestConfiguration {
 
 // produce isolated configurations for tests
 type TestContext struct {
-	cfg     Testable
-	baseCfg BaseTestConfiguration
+	cfg        Testable
+	baseCfg    BaseTestConfiguration
+	infra      InfraConfiguration
+	matrixName string
+
+	configServiceNodes int
 }
 
 func (ctx *TestContext) Configuration() Testable {
@@ -142,6 +201,27 @@ func (ctx *TestContext) BaseConfiguration() BaseTestConfiguration {
 	return ctx.baseCfg
 }
 
+// returns infrastructure images and credentials with defaults applied
+func (ctx *TestContext) Infra() InfraConfiguration {
+	return ctx.infra
+}
+
+// returns name of running matrix combination, empty if matrix is not used
+func (ctx *TestContext) MatrixName() string {
+	return ctx.matrixName
+}
+
+func (ctx *TestContext) withMatrixCombination(c MatrixCombination) *TestContext {
+	return &TestContext{
+		cfg:        ctx.cfg,
+		baseCfg:    ctx.baseCfg,
+		infra:      c.Infra.merge(ctx.infra),
+		matrixName: c.Name,
+
+		configServiceNodes: ctx.configServiceNodes,
+	}
+}
+
 // produce local configuration for config-service instance
 func (ctx *TestContext) GetConfigServiceConfiguration() ConfigServiceLocalConfiguration {
 	dbCfg := ctx.GetDBConfiguration()
@@ -170,21 +250,41 @@ func (ctx *TestContext) GetDBConfiguration() structure.DBConfiguration {
 	return structure.DBConfiguration{
 		Address:      fmt.Sprintf("%s-%s", pgSqlBaseHost, ctx.buildName()),
 		Port:         pgSqlPort,
-		Database:     PgSqlDbName,
-		Username:     PgSqlDbName,
-		Password:     PgSqlPassword,
+		Database:     ctx.infra.Postgres.Database,
+		Username:     ctx.infra.Postgres.Database,
+		Password:     ctx.infra.Postgres.Password,
 		CreateSchema: true,
 	}
 }
 
+func (ctx *TestContext) GetMongoConfiguration() structure.DBConfiguration {
+	return structure.DBConfiguration{
+		Address:  fmt.Sprintf("%s-%s", mongoBaseHost, ctx.buildName()),
+		Port:     mongoPort,
+		Database: ctx.infra.Mongo.Database,
+		Username: ctx.infra.Mongo.Username,
+		Password: ctx.infra.Mongo.Password,
+	}
+}
+
+func (ctx *TestContext) GetClickHouseConfiguration() structure.DBConfiguration {
+	return structure.DBConfiguration{
+		Address:  fmt.Sprintf("%s-%s", clickHouseBaseHost, ctx.buildName()),
+		Port:     clickHousePort,
+		Database: ctx.infra.ClickHouse.Database,
+		Username: ctx.infra.ClickHouse.Username,
+		Password: ctx.infra.ClickHouse.Password,
+	}
+}
+
 func (ctx *TestContext) GetRabbitConfiguration() mq.Config {
 	return mq.Config{
 		Address: event.AddressConfiguration{
 			IP:   fmt.Sprintf("%s-%s", rabbitBaseHost, ctx.buildName()),
 			Port: rabbitPort,
 		},
-		User:     rabbitUsername,
-		Password: rabbitPassword,
+		User:     ctx.infra.Rabbit.Username,
+		Password: ctx.infra.Rabbit.Password,
 	}
 }
 
@@ -197,11 +297,115 @@ func (ctx *TestContext) GetElasticConfiguration() structure.ElasticConfiguration
 	}
 }
 
+func (ctx *TestContext) GetRedisConfiguration() structure.RedisConfiguration {
+	return structure.RedisConfiguration{
+		Address: structure.AddressConfiguration{
+			IP:   fmt.Sprintf("%s-%s", redisBaseHost, ctx.buildName()),
+			Port: redisPort,
+		},
+		Password: ctx.infra.Redis.Password,
+	}
+}
+
+type KafkaConfiguration struct {
+	// broker addresses available from containers in the test network
+	Brokers []string
+	// broker addresses available from the test process
+	ExternalBrokers []string
+}
+
+func (ctx *TestContext) GetKafkaConfiguration() KafkaConfiguration {
+	return KafkaConfiguration{
+		Brokers: []string{fmt.Sprintf("%s-%s:%s", kafkaBaseHost, ctx.buildName(), KafkaPort)},
+	}
+}
+
+type ObjectStorageConfiguration struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+}
+
+// credentials are generated on container start
+func (ctx *TestContext) GetObjectStorageConfiguration() ObjectStorageConfiguration {
+	return ObjectStorageConfiguration{
+		Endpoint: fmt.Sprintf("%s-%s:%s", objectStorageBaseHost, ctx.buildName(), ObjectStoragePort),
+		Region:   objectStorageRegion,
+	}
+}
+
+// returns address of config-service, for cluster hosts and ports of all nodes are joined by ';'
+// as isp modules expect
 func (ctx *TestContext) GetConfigServiceAddress() structure.AddressConfiguration {
-	return structure.AddressConfiguration{
-		IP:   fmt.Sprintf("%s-%s", configServiceBaseHost, ctx.buildName()),
-		Port: configServiceHttpPort,
+	if ctx.configServiceNodes <= 1 {
+		return structure.AddressConfiguration{
+			IP:   fmt.Sprintf("%s-%s", configServiceBaseHost, ctx.buildName()),
+			Port: configServiceHttpPort,
+		}
+	}
+	addrs := make([]structure.AddressConfiguration, ctx.configServiceNodes)
+	for i := range addrs {
+		addrs[i] = structure.AddressConfiguration{IP: ctx.GetConfigServiceNodeName(i), Port: configServiceHttpPort}
+	}
+	return JoinAddresses(addrs)
+}
+
+// WithConfigServiceNodes returns copy of context for config-service cluster of n nodes,
+// addresses returned by GetConfigServiceAddress and GetModuleLocalConfig of the copy include all nodes
+func (ctx *TestContext) WithConfigServiceNodes(n int) *TestContext {
+	copied := *ctx
+	copied.configServiceNodes = n
+	return &copied
+}
+
+func (ctx *TestContext) GetConfigServiceNodeName(i int) string {
+	return fmt.Sprintf("%s-%d-%s", configServiceBaseHost, i, ctx.buildName())
+}
+
+// produce local configuration for i-th node of config-service cluster
+// all nodes bootstrap cluster with the same peers
+func (ctx *TestContext) GetConfigServiceNodeConfiguration(i int) ConfigServiceLocalConfiguration {
+	cfg := ctx.GetConfigServiceConfiguration()
+	cfg.GrpcOuterAddress.IP = ctx.GetConfigServiceNodeName(i)
+	peers := make([]string, ctx.configServiceNodes)
+	for j := range peers {
+		peers[j] = net.JoinHostPort(ctx.GetConfigServiceNodeName(j), configServiceRaftPort)
+	}
+	cfg.Cluster = ConfigServiceClusterConfiguration{
+		BootstrapCluster:      true,
+		OuterAddress:          net.JoinHostPort(cfg.GrpcOuterAddress.IP, configServiceRaftPort),
+		Peers:                 strings.Join(peers, ","),
+		ConnectTimeoutSeconds: 10,
 	}
+	return cfg
+}
+
+// JoinAddresses joins hosts and ports by ';', e.g. to set several config-service addresses
+func JoinAddresses(addrs []structure.AddressConfiguration) structure.AddressConfiguration {
+	hosts := make([]string, len(addrs))
+	ports := make([]string, len(addrs))
+	for i, addr := range addrs {
+		hosts[i] = addr.IP
+		ports[i] = addr.Port
+	}
+	return structure.AddressConfiguration{IP: strings.Join(hosts, ";"), Port: strings.Join(ports, ";")}
+}
+
+// SplitAddresses is the reverse of JoinAddresses
+func SplitAddresses(addr structure.AddressConfiguration) []structure.AddressConfiguration {
+	hosts := strings.Split(addr.IP, ";")
+	ports := strings.Split(addr.Port, ";")
+	result := make([]structure.AddressConfiguration, len(hosts))
+	for i, host := range hosts {
+		port := ports[len(ports)-1]
+		if i < len(ports) {
+			port = ports[i]
+		}
+		result[i] = structure.AddressConfiguration{IP: host, Port: port}
+	}
+	return result
 }
 
 func (ctx *TestContext) GetDockerNetwork() string {
@@ -217,6 +421,18 @@ func (ctx *TestContext) GetModuleLocalConfig(port, moduleName string) DefaultLoc
 	}
 }
 
+// produce local configuration for i-th instance of module
+// replicas share module name but have unique container names and outer addresses
+func (ctx *TestContext) GetModuleReplicaLocalConfig(port, moduleName string, replica int) DefaultLocalConfiguration {
+	cfg := ctx.GetModuleLocalConfig(port, moduleName)
+	cfg.GrpcOuterAddress.IP = ctx.GetReplicaContainer(moduleName, replica)
+	return cfg
+}
+
+func (ctx *TestContext) GetReplicaContainer(moduleName string, replica int) string {
+	return ctx.GetContainer(fmt.Sprintf("%s-%d", moduleName, replica))
+}
+
 func (ctx *TestContext) GetImage(imageName string) string {
 	return fmt.Sprintf("%s/%s", ctx.baseCfg.Registry.Host, imageName)
 }
@@ -226,6 +442,9 @@ func (ctx *TestContext) GetContainer(baseContainerName string) string {
 }
 
 func (ctx *TestContext) buildName() string {
+	if ctx.matrixName != "" {
+		return ctx.baseCfg.ModuleName + CurrentSessionName() + "-" + ctx.matrixName
+	}
 	return ctx.baseCfg.ModuleName + CurrentSessionName()
 }
 
@@ -266,9 +485,11 @@ func loadCtx(configPtr Testable) (*TestContext, error) {
 		return nil, err
 	}
 
+	baseCfg := configPtr.GetBaseConfiguration()
 	return &TestContext{
 		cfg:     configPtr,
-		baseCfg: configPtr.GetBaseConfiguration(),
+		baseCfg: baseCfg,
+		infra:   baseCfg.Infra.merge(defaultInfraConfiguration()),
 	}, nil
 }
 
@@ -58,13 +58,13 @@ func (te *TestEnvironment) makeBackupFile() {
 
 func (te *TestEnvironment) updateBackup() {
 	for _, container := range te.basicContainers {
-		if _, ok := te.backup.BasicContainers[containerId(container.containerId)]; !ok {
-			te.backup.BasicContainers[containerId(container.containerId)] = imageId(container.imageId)
+		if _, ok := te.backup.BasicContainers[containerId(container.id())]; !ok {
+			te.backup.BasicContainers[containerId(container.id())] = imageId(container.imageId)
 		}
 	}
 	for _, container := range te.appContainers {
-		if _, ok := te.backup.AppContainers[containerId(container.containerId)]; !ok {
-			te.backup.AppContainers[containerId(container.containerId)] = imageId(container.imageId)
+		if _, ok := te.backup.AppContainers[containerId(container.id())]; !ok {
+			te.backup.AppContainers[containerId(container.id())] = imageId(container.imageId)
 		}
 	}
 	if te.network != nil {
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	configUtils "github.com/integration-system/isp-lib-test/utils/config"
 	"github.com/integration-system/isp-lib/v2/config"
 	"github.com/pkg/errors"
 )
@@ -42,11 +44,14 @@ func (c *ispDockerClient) GetBridgeAddress() (string, error) {
 
 // create and run postgreSQL container
 // expect image from https://hub.docker.com/_/postgres
+// use WithPG* options to set init scripts and server settings, extensions are created by TestEnvironment.RunPGContainer
 func (c *ispDockerClient) RunPGContainer(image string, dbAndUserName string, password string, opts ...Option) (*ContainerContext, error) {
 	vars := []string{
 		fmt.Sprintf("POSTGRES_USER=%s", dbAndUserName),
 		fmt.Sprintf("POSTGRES_PASSWORD=%s", password),
 	}
+	ops := newOptions(opts)
+	opts = append(opts, ops.pg.containerOptions(ops.cmd)...)
 	return c.runContainer(image, vars, opts...)
 }
 
@@ -57,7 +62,30 @@ func (c *ispDockerClient) RunContainer(image string, opts ...Option) (*Container
 
 // create and run isp application container, override local and remote configuration through environment variables
 // localConfig and remoteConfig can be map or struct
+// use WithRemoteConfigSchema or WithPublishedRemoteConfigSchema option to validate remoteConfig before run,
+// published schema is used only if module has already published it
 func (c *ispDockerClient) RunAppContainer(image string, localConfig, remoteConfig interface{}, opts ...Option) (*ContainerContext, error) {
+	ops := newOptions(opts)
+	if ops.remoteConfigSchema != "" && remoteConfig != nil {
+		schema, err := configUtils.LoadSchemaFile(ops.remoteConfigSchema)
+		if err != nil {
+			return &ContainerContext{client: c}, err
+		}
+		if err := configUtils.ValidateConfig(schema, remoteConfig); err != nil {
+			return &ContainerContext{client: c}, err
+		}
+	}
+	if ops.publishedSchema != nil && remoteConfig != nil {
+		schema, err := ops.publishedSchema.load()
+		if err != nil {
+			return &ContainerContext{client: c}, err
+		}
+		if schema != nil {
+			if err := configUtils.ValidateConfig(schema, remoteConfig); err != nil {
+				return &ContainerContext{client: c}, errors.WithMessagef(err, "module %s", ops.publishedSchema.moduleName)
+			}
+		}
+	}
 	vars := make([]string, 0)
 	if localConfig != nil {
 		vars = append(vars, configToEnvVariables(localConfig, config.LocalConfigEnvPrefix, false)...)
@@ -89,10 +117,7 @@ func (c *ispDockerClient) CreateNetwork(name string) (*NetworkContext, error) {
 // dont pull image by default, use option PullImage to pull first
 // never return nil ContainerContext
 func (c *ispDockerClient) runContainer(image string, envVars []string, opts ...Option) (*ContainerContext, error) {
-	ops := &options{}
-	for _, v := range opts {
-		v(ops)
-	}
+	ops := newOptions(opts)
 	if ops.imageName != "" {
 		image = ops.imageName
 	}
@@ -131,16 +156,40 @@ func (c *ispDockerClient) runContainer(image string, envVars []string, opts ...O
 		}
 		hostCfg.Binds = ops.volume
 	}
+	if len(ops.tmpfs) > 0 {
+		if hostCfg == nil {
+			hostCfg = &container.HostConfig{}
+		}
+		hostCfg.Tmpfs = ops.tmpfs
+	}
 	resp, err := c.c.ContainerCreate(context.Background(), &container.Config{
 		Image:        image,
 		Env:          envVars,
 		ExposedPorts: ops.portSet,
+		Entrypoint:   ops.entrypoint,
+		Cmd:          ops.cmd,
 	}, hostCfg, nil, nil, ops.name)
 	if err != nil {
 		return ctx, errors.Wrap(err, "create container")
 	}
 
-	ctx.containerId = resp.ID
+	ctx.setId(resp.ID)
+
+	containerName := ops.name
+	if containerName == "" {
+		containerName = shortId(resp.ID)
+	}
+	ctx.name = containerName
+	ctx.logs = newLogCollector(ops.logBufferSize)
+	for i := range ops.logSinks {
+		ops.logSinks[i].timestamps = ops.logSinks[i].timestamps || ops.logTimestamps
+	}
+	if err := ctx.logs.setSinks(ops.logSinks, containerName); err != nil {
+		return ctx, err
+	}
+	if ops.onCreate != nil {
+		ops.onCreate(ctx)
+	}
 
 	if ops.networkId != "" {
 		if err := c.c.NetworkConnect(context.Background(), ops.networkId, resp.ID, nil); err != nil {
@@ -148,6 +197,12 @@ func (c *ispDockerClient) runContainer(image string, envVars []string, opts ...O
 		}
 	}
 
+	if len(ops.files) > 0 {
+		if err := c.copyFiles(resp.ID, ops.files); err != nil {
+			return ctx, err
+		}
+	}
+
 	err = c.c.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{})
 	if err != nil {
 		return ctx, errors.Wrap(err, "start container")
@@ -161,19 +216,8 @@ func (c *ispDockerClient) runContainer(image string, envVars []string, opts ...O
 		ctx.ipAddr = containerInfo.NetworkSettings.Networks[ops.networkName].IPAddress
 	}
 
-	if ops.logger != nil {
-		reader, err := c.c.ContainerLogs(
-			context.Background(),
-			resp.ID,
-			types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true},
-		)
-		if err != nil {
-			return ctx, errors.Wrap(err, "attach logger")
-		}
-		ctx.logger = ops.logger
-		go func() {
-			_, _ = io.Copy(ops.logger, reader)
-		}()
+	if err := ctx.followLogs(time.Time{}); err != nil {
+		return ctx, errors.Wrap(err, "attach logger")
 	}
 	ctx.started = true
 	return ctx, nil
@@ -0,0 +1,180 @@
+package docker
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/integration-system/isp-lib-test/ctx"
+	"github.com/integration-system/isp-lib/v2/structure"
+	"github.com/pkg/errors"
+)
+
+var (
+	// config-service nodes log raft state transitions, default matches hashicorp raft messages
+	ConfigServiceRaftStateLogRegexp = regexp.MustCompile(`(?i)entering (leader|follower|candidate) state`)
+	// subset of state transitions meaning that node became the leader
+	ConfigServiceLeaderLogRegexp = regexp.MustCompile(`(?i)entering leader state`)
+)
+
+const leaderPollInterval = 200 * time.Millisecond
+
+// ConfigServiceCluster is a set of config-service nodes forming raft cluster
+type ConfigServiceCluster struct {
+	te      *TestEnvironment
+	testCtx *ctx.TestContext
+	nodes   []*ContainerContext
+
+	mu          sync.Mutex
+	partitioned map[*ContainerContext]bool
+}
+
+// run n config-service nodes with peers addressed by container names on the session network
+// use ConfigServiceCluster.TestContext to get configurations with addresses of all nodes,
+// test context of environment is not changed
+// returned address contains ip addresses of all nodes joined by ';', it can be passed to utils/config.Wait
+func (te *TestEnvironment) RunConfigServiceCluster(n int, opts ...Option) (*ConfigServiceCluster, structure.AddressConfiguration) {
+	if n < 1 {
+		panic(errors.Errorf("invalid config service cluster size %d", n))
+	}
+	cluster := &ConfigServiceCluster{
+		te:          te,
+		testCtx:     te.testCtx.WithConfigServiceNodes(n),
+		partitioned: make(map[*ContainerContext]bool),
+	}
+	for i := 0; i < n; i++ {
+		nodeOpts := []Option{WithName(cluster.testCtx.GetConfigServiceNodeName(i))}
+		if i == 0 {
+			nodeOpts = append(nodeOpts, PullImage(te.cfg.Registry.Username, te.cfg.Registry.Password))
+		}
+		nodeOpts = append(nodeOpts, opts...)
+		node := te.RunAppContainer(te.cfg.Images.ConfigService,
+			cluster.testCtx.GetConfigServiceNodeConfiguration(i),
+			nil,
+			nodeOpts...,
+		)
+		cluster.nodes = append(cluster.nodes, node)
+	}
+	te.makeBackupFile()
+	return cluster, cluster.Address()
+}
+
+// TestContext returns copy of environment test context,
+// its GetConfigServiceAddress and GetModuleLocalConfig return addresses of all nodes joined by ';'
+func (c *ConfigServiceCluster) TestContext() *ctx.TestContext {
+	return c.testCtx
+}
+
+func (c *ConfigServiceCluster) Nodes() []*ContainerContext {
+	return append([]*ContainerContext(nil), c.nodes...)
+}
+
+func (c *ConfigServiceCluster) Node(i int) *ContainerContext {
+	return c.nodes[i]
+}
+
+// Address returns addresses of all nodes reachable from the host joined by ';'
+func (c *ConfigServiceCluster) Address() structure.AddressConfiguration {
+	addrs := make([]structure.AddressConfiguration, len(c.nodes))
+	for i := range c.nodes {
+		addrs[i] = c.NodeAddress(i)
+	}
+	return ctx.JoinAddresses(addrs)
+}
+
+// NodeAddress returns address of i-th node reachable from the host
+func (c *ConfigServiceCluster) NodeAddress(i int) structure.AddressConfiguration {
+	addr := c.testCtx.GetConfigServiceConfiguration().WS.Rest
+	addr.IP = c.nodes[i].GetIPAddress()
+	return addr
+}
+
+// Leader returns running and connected node which reported leadership last
+func (c *ConfigServiceCluster) Leader() (*ContainerContext, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var leader *ContainerContext
+	var leaderSince time.Time
+	for _, node := range c.nodes {
+		if !node.started || c.partitioned[node] {
+			continue
+		}
+		states := node.GrepLogs(ConfigServiceRaftStateLogRegexp)
+		if len(states) == 0 {
+			continue
+		}
+		last := states[len(states)-1]
+		if ConfigServiceLeaderLogRegexp.MatchString(last.Line) && last.Time.After(leaderSince) {
+			leader = node
+			leaderSince = last.Time
+		}
+	}
+	if leader == nil {
+		return nil, errors.New("config service cluster has no leader")
+	}
+	return leader, nil
+}
+
+// WaitLeader waits until some available node becomes the leader
+func (c *ConfigServiceCluster) WaitLeader(timeout time.Duration) (*ContainerContext, error) {
+	return c.WaitNewLeader(nil, timeout)
+}
+
+// WaitNewLeader waits until available node other than old becomes the leader
+func (c *ConfigServiceCluster) WaitNewLeader(old *ContainerContext, timeout time.Duration) (*ContainerContext, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		leader, err := c.Leader()
+		if err == nil && leader != old {
+			return leader, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("new config service leader is not elected after %s", timeout)
+		}
+		time.Sleep(leaderPollInterval)
+	}
+}
+
+// KillLeader kills current leader node, use StartContainer on returned node to bring it back
+func (c *ConfigServiceCluster) KillLeader() (*ContainerContext, error) {
+	leader, err := c.Leader()
+	if err != nil {
+		return nil, err
+	}
+	return leader, leader.Kill()
+}
+
+// PartitionLeader disconnects current leader node from the session network, node keeps running
+// use Heal to connect it back
+func (c *ConfigServiceCluster) PartitionLeader() (*ContainerContext, error) {
+	leader, err := c.Leader()
+	if err != nil {
+		return nil, err
+	}
+	return leader, c.Partition(leader)
+}
+
+// Partition disconnects node from the session network
+func (c *ConfigServiceCluster) Partition(node *ContainerContext) error {
+	if err := node.DisconnectNetwork(c.te.network); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.partitioned[node] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// Heal connects all partitioned nodes back to the session network
+func (c *ConfigServiceCluster) Heal() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for node := range c.partitioned {
+		if err := node.ConnectNetwork(c.te.network); err != nil {
+			return err
+		}
+		delete(c.partitioned, node)
+	}
+	return nil
+}
@@ -1,7 +1,15 @@
 package docker
 
+import "github.com/integration-system/isp-lib-test/ctx"
+
+// default images, can be overridden by BaseTestConfiguration.Infra
 const (
-	DefaultPGImage      = "docker.io/library/postgres:alpine"
-	DefaultRabbitImage  = "docker.io/library/rabbitmq:alpine"
-	DefaultElasticImage = "docker.io/library/elasticsearch:6.8.4"
+	DefaultPGImage         = ctx.DefaultPGImage
+	DefaultRabbitImage     = ctx.DefaultRabbitImage
+	DefaultElasticImage    = ctx.DefaultElasticImage
+	DefaultRedisImage      = ctx.DefaultRedisImage
+	DefaultKafkaImage      = ctx.DefaultKafkaImage
+	DefaultMinioImage      = ctx.DefaultMinioImage
+	DefaultMongoImage      = ctx.DefaultMongoImage
+	DefaultClickHouseImage = ctx.DefaultClickHouseImage
 )
@@ -1,22 +1,29 @@
 package docker
 
 import (
+	"bytes"
 	"context"
-	"io"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/pkg/errors"
 )
 
 type ContainerContext struct {
 	imageId     string
+	idMu        sync.RWMutex
 	containerId string
+	name        string
 	client      *ispDockerClient
 	ipAddr      string
 	started     bool
-	logger      io.Writer
+	logs        *logCollector
+
+	exitExpectedFlag int32
 }
 
 // force delete container and image
@@ -36,16 +43,22 @@ func (ctx *ContainerContext) Close() error {
 }
 
 func (ctx *ContainerContext) ForceRemoveContainer() error {
-	if ctx.containerId != "" {
+	if ctx.id() != "" {
+		ctx.expectExit(true)
 		err := ctx.client.c.ContainerRemove(
 			context.Background(),
-			ctx.containerId,
+			ctx.id(),
 			types.ContainerRemoveOptions{Force: true, RemoveVolumes: true},
 		)
 		if err != nil {
 			return errors.Wrap(err, "container remove")
 		}
-		ctx.containerId = ""
+		ctx.setId("")
+		if ctx.logs != nil {
+			if err := ctx.logs.close(); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -70,10 +83,11 @@ func (ctx *ContainerContext) ForceRemoveImage() error {
 // StopContainer stops a container without terminating the process.
 // The process is blocked until the container stops or the timeout expires.
 func (ctx *ContainerContext) StopContainer(timeout time.Duration) error {
-	if ctx.containerId != "" && ctx.started {
+	if ctx.id() != "" && ctx.started {
+		ctx.expectExit(true)
 		err := ctx.client.c.ContainerStop(
 			context.Background(),
-			ctx.containerId,
+			ctx.id(),
 			&timeout,
 		)
 		if err != nil {
@@ -86,34 +100,150 @@ func (ctx *ContainerContext) StopContainer(timeout time.Duration) error {
 
 // StartContainer sends a request to the docker daemon to start a container.
 func (ctx *ContainerContext) StartContainer() error {
-	if ctx.containerId != "" && !ctx.started {
+	if ctx.id() != "" && !ctx.started {
+		ctx.expectExit(false)
 		err := ctx.client.c.ContainerStart(
 			context.Background(),
-			ctx.containerId,
+			ctx.id(),
 			types.ContainerStartOptions{},
 		)
 		if err != nil {
 			return errors.Wrap(err, "container start")
 		}
-		if ctx.logger != nil {
-			now := time.Now().Format("2006-01-02T15:04:05")
-			reader, err := ctx.client.c.ContainerLogs(
-				context.Background(),
-				ctx.containerId,
-				types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Since: now},
-			)
-			if err != nil {
+		if ctx.logs != nil {
+			// resume after the last collected line, output before restart is kept by docker
+			if err := ctx.followLogs(ctx.logs.latest()); err != nil {
 				return errors.Wrap(err, "attach container logger")
 			}
-			go func() {
-				_, _ = io.Copy(ctx.logger, reader)
-			}()
 		}
 	}
 	ctx.started = true
 	return nil
 }
 
+// Kill sends SIGKILL to container process, use StartContainer to start it again
+func (ctx *ContainerContext) Kill() error {
+	if ctx.id() != "" && ctx.started {
+		ctx.expectExit(true)
+		err := ctx.client.c.ContainerKill(context.Background(), ctx.id(), "SIGKILL")
+		if err != nil {
+			return errors.Wrap(err, "container kill")
+		}
+	}
+	ctx.started = false
+	return nil
+}
+
+// DisconnectNetwork detaches container from network, container keeps running
+func (ctx *ContainerContext) DisconnectNetwork(net *NetworkContext) error {
+	err := ctx.client.c.NetworkDisconnect(context.Background(), net.id, ctx.id(), true)
+	if err != nil {
+		return errors.Wrap(err, "network disconnect")
+	}
+	return nil
+}
+
+// ConnectNetwork attaches container to network, ip address of container may change
+// other containers still reach it by name
+func (ctx *ContainerContext) ConnectNetwork(net *NetworkContext) error {
+	err := ctx.client.c.NetworkConnect(context.Background(), net.id, ctx.id(), nil)
+	if err != nil {
+		return errors.Wrap(err, "network connect")
+	}
+	containerInfo, err := ctx.client.c.ContainerInspect(context.Background(), ctx.id())
+	if err != nil {
+		return errors.Wrap(err, "container inspect")
+	}
+	if settings, ok := containerInfo.NetworkSettings.Networks[net.name]; ok {
+		ctx.ipAddr = settings.IPAddress
+	}
+	return nil
+}
+
+// Exec runs command in running container and returns its exit code and combined stdout and stderr
+func (ctx *ContainerContext) Exec(cmd ...string) (int, []byte, error) {
+	execCtx := context.Background()
+	created, err := ctx.client.c.ContainerExecCreate(execCtx, ctx.id(), types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "exec create")
+	}
+	attached, err := ctx.client.c.ContainerExecAttach(execCtx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "exec attach")
+	}
+	defer attached.Close()
+	output := new(bytes.Buffer)
+	if _, err := stdcopy.StdCopy(output, output, attached.Reader); err != nil {
+		return 0, nil, errors.Wrap(err, "read exec output")
+	}
+	inspect, err := ctx.client.c.ContainerExecInspect(execCtx, created.ID)
+	if err != nil {
+		return 0, output.Bytes(), errors.Wrap(err, "exec inspect")
+	}
+	return inspect.ExitCode, output.Bytes(), nil
+}
+
+// container id is read by events and stats goroutines, use id and setId to access it
+func (ctx *ContainerContext) id() string {
+	ctx.idMu.RLock()
+	defer ctx.idMu.RUnlock()
+	return ctx.containerId
+}
+
+func (ctx *ContainerContext) setId(id string) {
+	ctx.idMu.Lock()
+	defer ctx.idMu.Unlock()
+	ctx.containerId = id
+}
+
 func (ctx *ContainerContext) GetIPAddress() string {
 	return ctx.ipAddr
 }
+
+// returns container name, or short container id if name was not specified
+func (ctx *ContainerContext) Name() string {
+	return ctx.name
+}
+
+// read all container output written so far, stdout and stderr are combined
+func (ctx *ContainerContext) output() ([]byte, error) {
+	return ctx.readOutput("all")
+}
+
+// read last n lines of container output
+func (ctx *ContainerContext) lastLines(n int) ([]string, error) {
+	data, err := ctx.readOutput(strconv.Itoa(n))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n"), nil
+}
+
+func (ctx *ContainerContext) readOutput(tail string) ([]byte, error) {
+	reader, err := ctx.client.c.ContainerLogs(
+		context.Background(),
+		ctx.id(),
+		types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Tail: tail},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "container logs")
+	}
+	defer reader.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := stdcopy.StdCopy(buf, buf, reader); err != nil {
+		return nil, errors.Wrap(err, "read container logs")
+	}
+	return buf.Bytes(), nil
+}
+
+func shortId(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,155 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/hashicorp/go-multierror"
+)
+
+const crashLogLines = 50
+
+type CrashPolicy int
+
+const (
+	// fail the currently running test, tests started after the crash fail immediately
+	CrashFailTest CrashPolicy = iota
+	// cleanup environment and exit with non-zero code
+	CrashAbortRun
+	// only record crash, see TestEnvironment.Crashes
+	CrashRecordOnly
+)
+
+// CrashError describes unexpected container exit
+type CrashError struct {
+	Container string
+	Event     string
+	ExitCode  int
+	OOMKilled bool
+	Time      time.Time
+	LastLogs  []string
+}
+
+func (e *CrashError) Error() string {
+	reason := fmt.Sprintf("exit code %d", e.ExitCode)
+	if e.OOMKilled {
+		reason = "killed by OOM"
+	}
+	msg := fmt.Sprintf("container %s unexpectedly stopped at %s: %s",
+		e.Container, e.Time.Format(logTimeLayout), reason)
+	if len(e.LastLogs) > 0 {
+		msg = fmt.Sprintf("%s, last log lines:\n%s", msg, strings.Join(e.LastLogs, "\n"))
+	}
+	return msg
+}
+
+// SetCrashPolicy changes reaction on unexpected container exit, CrashFailTest by default
+func (te *TestEnvironment) SetCrashPolicy(policy CrashPolicy) {
+	te.crashMu.Lock()
+	defer te.crashMu.Unlock()
+	te.crashPolicy = policy
+}
+
+// Crashes returns all recorded unexpected container exits
+func (te *TestEnvironment) Crashes() []*CrashError {
+	te.crashMu.Lock()
+	defer te.crashMu.Unlock()
+	return append([]*CrashError(nil), te.crashes...)
+}
+
+// CrashErr returns all recorded unexpected container exits as single error or nil
+func (te *TestEnvironment) CrashErr() error {
+	var errs *multierror.Error
+	for _, crash := range te.Crashes() {
+		errs = multierror.Append(errs, crash)
+	}
+	return errs.ErrorOrNil()
+}
+
+func (te *TestEnvironment) watchEvents(ctx context.Context) {
+	args := filters.NewArgs()
+	args.Add("type", events.ContainerEventType)
+	args.Add("event", "die")
+	args.Add("event", "oom")
+	messages, errs := te.cli.c.Events(ctx, types.EventsOptions{Filters: args})
+	for {
+		select {
+		case msg := <-messages:
+			te.handleEvent(msg)
+		case err := <-errs:
+			if err != nil && ctx.Err() == nil {
+				fmt.Printf("docker events subscription was closed: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+func (te *TestEnvironment) handleEvent(msg events.Message) {
+	container := te.findContainer(msg.Actor.ID)
+	if container == nil || container.exitExpected() {
+		return
+	}
+
+	crash := &CrashError{
+		Container: container.Name(),
+		Event:     msg.Action,
+		OOMKilled: msg.Action == "oom",
+		Time:      time.Unix(0, msg.TimeNano),
+	}
+	if state, err := container.State(); err == nil {
+		crash.ExitCode = state.ExitCode
+		crash.OOMKilled = crash.OOMKilled || state.OOMKilled
+	} else if code, err := strconv.Atoi(msg.Actor.Attributes["exitCode"]); err == nil {
+		crash.ExitCode = code
+	}
+	crash.LastLogs, _ = container.lastLines(crashLogLines)
+
+	te.crashMu.Lock()
+	defer te.crashMu.Unlock()
+	te.crashes = append(te.crashes, crash)
+	switch te.crashPolicy {
+	case CrashFailTest:
+		if te.currentTest != nil {
+			te.currentTest.Errorf("%v", crash)
+		}
+	case CrashAbortRun:
+		fmt.Printf("abort test run: %v\n", crash)
+		go func() {
+			if err := te.Cleanup(); err != nil {
+				fmt.Printf("Cleanup() was returned an error: %v", err)
+			}
+			os.Exit(1)
+		}()
+	}
+}
+
+func (te *TestEnvironment) findContainer(id string) *ContainerContext {
+	for _, container := range te.containers() {
+		if container.id() == id {
+			return container
+		}
+	}
+	return nil
+}
+
+// mark container as going to be stopped, its exit is not treated as crash
+func (ctx *ContainerContext) expectExit(expected bool) {
+	var value int32
+	if expected {
+		value = 1
+	}
+	atomic.StoreInt32(&ctx.exitExpectedFlag, value)
+}
+
+func (ctx *ContainerContext) exitExpected() bool {
+	return atomic.LoadInt32(&ctx.exitExpectedFlag) == 1
+}
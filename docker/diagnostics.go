@@ -0,0 +1,194 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-multierror"
+	"github.com/integration-system/isp-lib-test/ctx"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DiagnosticsDirEnv     = "ISP_TEST_ARTIFACTS_DIR"
+	DefaultDiagnosticsDir = "test-artifacts"
+
+	maskedValue = "******"
+)
+
+var secretKeyRegexp = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|api_?key|private)`)
+
+// EnableDiagnostics enables writing of diagnostics bundle on test failure and on Cleanup
+// bundle is written into dir/<session>/<test name>, if dir is empty
+// ISP_TEST_ARTIFACTS_DIR env variable or ./test-artifacts is used
+func (te *TestEnvironment) EnableDiagnostics(dir string) {
+	if dir == "" {
+		dir = os.Getenv(DiagnosticsDirEnv)
+	}
+	if dir == "" {
+		dir = DefaultDiagnosticsDir
+	}
+	te.diagnosticsDir = dir
+}
+
+// CollectDiagnostics writes logs, inspect and stats of each container, network inspect
+// and effective test configuration into separate directory, returns path to the directory
+func (te *TestEnvironment) CollectDiagnostics(name string) (string, error) {
+	baseDir := te.diagnosticsDir
+	if baseDir == "" {
+		baseDir = DefaultDiagnosticsDir
+	}
+	dir := filepath.Join(baseDir, ctx.CurrentSessionName(), sanitizeFileName(name))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return dir, errors.Wrap(err, "create diagnostics dir")
+	}
+
+	var errs *multierror.Error
+	for _, container := range te.containers() {
+		if container.id() == "" {
+			continue
+		}
+		err := container.writeDiagnostics(filepath.Join(dir, sanitizeFileName(container.Name())))
+		errs = multierror.Append(errs, err)
+	}
+
+	if te.network != nil && te.network.id != "" {
+		network, err := te.cli.c.NetworkInspect(context.Background(), te.network.id, types.NetworkInspectOptions{Verbose: true})
+		if err != nil {
+			errs = multierror.Append(errs, errors.Wrap(err, "network inspect"))
+		} else {
+			errs = multierror.Append(errs, writeJSON(filepath.Join(dir, "network.json"), network))
+		}
+	}
+
+	errs = multierror.Append(errs, te.writeTestConfig(filepath.Join(dir, "config_test.yml")))
+	return dir, errs.ErrorOrNil()
+}
+
+func (te *TestEnvironment) collectDiagnosticsIfEnabled(name string) {
+	if te.diagnosticsDir == "" {
+		return
+	}
+	dir, err := te.CollectDiagnostics(name)
+	if err != nil {
+		fmt.Printf("diagnostics were partially written to %s: %v\n", dir, err)
+		return
+	}
+	fmt.Printf("diagnostics were written to %s\n", dir)
+}
+
+func (te *TestEnvironment) writeTestConfig(path string) error {
+	data, err := yaml.Marshal(te.testCtx.Configuration())
+	if err != nil {
+		return errors.Wrap(err, "marshal test config")
+	}
+	var cfg interface{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return errors.Wrap(err, "unmarshal test config")
+	}
+	data, err = yaml.Marshal(maskSecrets(cfg))
+	if err != nil {
+		return errors.Wrap(err, "marshal test config")
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func (ctx *ContainerContext) writeDiagnostics(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "create container diagnostics dir")
+	}
+
+	var errs *multierror.Error
+	logs, err := ctx.output()
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	} else {
+		errs = multierror.Append(errs, ioutil.WriteFile(filepath.Join(dir, "logs.txt"), logs, 0644))
+	}
+
+	info, err := ctx.client.c.ContainerInspect(context.Background(), ctx.id())
+	if err != nil {
+		errs = multierror.Append(errs, errors.Wrap(err, "container inspect"))
+	} else {
+		env := make([]string, 0)
+		if info.Config != nil {
+			env = maskEnv(info.Config.Env)
+			info.Config.Env = env
+		}
+		errs = multierror.Append(errs, writeJSON(filepath.Join(dir, "inspect.json"), info))
+		errs = multierror.Append(errs, ioutil.WriteFile(filepath.Join(dir, "env.txt"), []byte(strings.Join(env, "\n")), 0644))
+	}
+
+	if info.ContainerJSONBase == nil || info.State == nil || info.State.Running {
+		stats, err := ctx.client.c.ContainerStats(context.Background(), ctx.id(), false)
+		if err != nil {
+			errs = multierror.Append(errs, errors.Wrap(err, "container stats"))
+		} else {
+			data, err := ioutil.ReadAll(stats.Body)
+			_ = stats.Body.Close()
+			if err != nil {
+				errs = multierror.Append(errs, errors.Wrap(err, "read container stats"))
+			} else {
+				errs = multierror.Append(errs, ioutil.WriteFile(filepath.Join(dir, "stats.json"), data, 0644))
+			}
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func maskEnv(env []string) []string {
+	result := make([]string, 0, len(env))
+	for _, v := range env {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 && secretKeyRegexp.MatchString(parts[0]) {
+			v = parts[0] + "=" + maskedValue
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+func maskSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		for key, item := range v {
+			if secretKeyRegexp.MatchString(fmt.Sprint(key)) && item != nil && fmt.Sprint(item) != "" {
+				v[key] = maskedValue
+			} else {
+				v[key] = maskSecrets(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = maskSecrets(item)
+		}
+	}
+	return value
+}
+
+func writeJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "marshal %s", filepath.Base(path))
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+}
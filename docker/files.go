@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+const defaultFileMode os.FileMode = 0644
+
+type containerFile struct {
+	path     string
+	content  []byte
+	template string
+	data     interface{}
+	mode     os.FileMode
+}
+
+func (f containerFile) render() ([]byte, error) {
+	if f.template == "" {
+		return f.content, nil
+	}
+	tmpl, err := template.New(path.Base(f.path)).Parse(f.template)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse template file %s", f.path)
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, f.data); err != nil {
+		return nil, errors.Wrapf(err, "render template file %s", f.path)
+	}
+	return buf.Bytes(), nil
+}
+
+// pack files into tar archive and extract it into container root
+func (c *ispDockerClient) copyFiles(containerId string, files []containerFile) error {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	now := time.Now()
+	for _, file := range files {
+		content, err := file.render()
+		if err != nil {
+			return err
+		}
+		mode := file.mode
+		if mode == 0 {
+			mode = defaultFileMode
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    strings.TrimPrefix(path.Clean(file.path), "/"),
+			Mode:    int64(mode.Perm()),
+			Size:    int64(len(content)),
+			ModTime: now,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "write file %s header", file.path)
+		}
+		if _, err := tw.Write(content); err != nil {
+			return errors.Wrapf(err, "write file %s", file.path)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "close files archive")
+	}
+
+	err := c.c.CopyToContainer(context.Background(), containerId, "/", buf, types.CopyToContainerOptions{})
+	if err != nil {
+		return errors.Wrap(err, "copy files to container")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"github.com/integration-system/isp-lib/v2/structure"
+	"github.com/pkg/errors"
+)
+
+// run system service connected to the session config-service, postgres and redis
+// returns grpc address of system service reachable from the host, use utils/system to provision applications
+func (te *TestEnvironment) RunSystemServiceContainer(opts ...Option) (*ContainerContext, structure.AddressConfiguration) {
+	if te.cfg.Images.SystemService == "" {
+		panic(errors.New("system service image is not configured, set Images.SystemService"))
+	}
+	localCfg := te.testCtx.GetSystemServiceLocalConfig()
+	opts = append([]Option{
+		WithName(localCfg.ContainerName()),
+		PullImage(te.cfg.Registry.Username, te.cfg.Registry.Password),
+	}, opts...)
+	systemCtx := te.RunAppContainer(te.cfg.Images.SystemService,
+		localCfg,
+		te.testCtx.GetSystemServiceRemoteConfig(),
+		opts...,
+	)
+	addr := localCfg.GrpcOuterAddress
+	addr.IP = systemCtx.GetIPAddress()
+	return systemCtx, addr
+}
+
+// run gate connected to the session config-service and redis
+// returns http address of gate reachable from the host, use utils/gate to make authenticated calls
+func (te *TestEnvironment) RunGateContainer(opts ...Option) (*ContainerContext, structure.AddressConfiguration) {
+	if te.cfg.Images.Gate == "" {
+		panic(errors.New("gate image is not configured, set Images.Gate"))
+	}
+	localCfg := te.testCtx.GetGateLocalConfig()
+	opts = append([]Option{
+		WithName(localCfg.ContainerName()),
+		PullImage(te.cfg.Registry.Username, te.cfg.Registry.Password),
+	}, opts...)
+	gateCtx := te.RunAppContainer(te.cfg.Images.Gate,
+		localCfg,
+		te.testCtx.GetGateRemoteConfig(),
+		opts...,
+	)
+	addr := localCfg.HttpOuterAddress
+	addr.IP = gateCtx.GetIPAddress()
+	return gateCtx, addr
+}
@@ -0,0 +1,21 @@
+package docker
+
+import (
+	"github.com/integration-system/isp-lib-test/httpmock"
+)
+
+// start in-process HTTP mock server bound to the bridge address, so containers can call it
+// use Server.RemoteConfig to pass its url to app container
+// server is closed on Cleanup
+func (te *TestEnvironment) RunHttpMock() *httpmock.Server {
+	host, err := te.cli.GetBridgeAddress()
+	if err != nil {
+		panic(err)
+	}
+	server, err := httpmock.NewServer(host)
+	if err != nil {
+		panic(err)
+	}
+	te.addCloser(server)
+	return server
+}
@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/integration-system/isp-lib-test/ctx"
+	"github.com/pkg/errors"
+)
+
+const (
+	kafkaExternalPort = "9094"
+	// external advertised address is written to file after docker assigns host port
+	kafkaExternalFile = "/tmp/isp-test-kafka-external"
+)
+
+// waits for external advertised address, then runs bitnami entrypoint with image default command
+var kafkaEntrypoint = []string{"/bin/sh", "-c", `while [ ! -s ` + kafkaExternalFile + ` ]; do sleep 0.1; done
+export KAFKA_CFG_ADVERTISED_LISTENERS="$KAFKA_CFG_ADVERTISED_LISTENERS,EXTERNAL://$(cat ` + kafkaExternalFile + `)"
+exec "$@"`, "kafka"}
+
+var kafkaCmd = []string{"/opt/bitnami/scripts/kafka/entrypoint.sh", "/opt/bitnami/scripts/kafka/run.sh"}
+
+// RunKafkaContainer runs single node kafka in KRaft mode without zookeeper
+// INTERNAL listener is advertised by container name for containers in the test network,
+// EXTERNAL listener is bound to host port assigned by docker and advertised by docker host address for the test process
+// broker starts after host port is read back from container, so entrypoint and command of image are overridden,
+// docker may assign another host port after container restart, external address is not updated then
+func (te *TestEnvironment) RunKafkaContainer(opts ...Option) (*ContainerContext, ctx.KafkaConfiguration) {
+	kafkaCfg := te.testCtx.GetKafkaConfiguration()
+	containerName, _, err := net.SplitHostPort(kafkaCfg.Brokers[0])
+	if err != nil {
+		panic(err)
+	}
+	clusterId, err := kafkaClusterId()
+	if err != nil {
+		panic(err)
+	}
+
+	defaultOpts := []Option{
+		WithName(containerName),
+		WithNetwork(te.network),
+		PullImage("", ""),
+		WithPortBindings(map[string]string{"": kafkaExternalPort}),
+		WithEntrypoint(kafkaEntrypoint...),
+		WithCmd(kafkaCmd...),
+		WithEnv(map[string]string{
+			"ALLOW_PLAINTEXT_LISTENER":                           "yes",
+			"KAFKA_ENABLE_KRAFT":                                 "yes",
+			"KAFKA_KRAFT_CLUSTER_ID":                             clusterId,
+			"KAFKA_CFG_NODE_ID":                                  "1",
+			"KAFKA_CFG_BROKER_ID":                                "1",
+			"KAFKA_CFG_PROCESS_ROLES":                            "broker,controller",
+			"KAFKA_CFG_CONTROLLER_QUORUM_VOTERS":                 "1@localhost:9093",
+			"KAFKA_CFG_CONTROLLER_LISTENER_NAMES":                "CONTROLLER",
+			"KAFKA_CFG_INTER_BROKER_LISTENER_NAME":               "INTERNAL",
+			"KAFKA_CFG_LISTENERS":                                fmt.Sprintf("INTERNAL://:%s,CONTROLLER://:9093,EXTERNAL://:%s", ctx.KafkaPort, kafkaExternalPort),
+			"KAFKA_CFG_ADVERTISED_LISTENERS":                     fmt.Sprintf("INTERNAL://%s", kafkaCfg.Brokers[0]),
+			"KAFKA_CFG_LISTENER_SECURITY_PROTOCOL_MAP":           "INTERNAL:PLAINTEXT,CONTROLLER:PLAINTEXT,EXTERNAL:PLAINTEXT",
+			"KAFKA_CFG_AUTO_CREATE_TOPICS_ENABLE":                "true",
+			"KAFKA_CFG_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+			"KAFKA_CFG_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+			"KAFKA_CFG_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+		}),
+	}
+	defaultOpts = append(defaultOpts, opts...)
+	kafkaCtx, err := te.cli.RunContainer(
+		te.testCtx.Infra().Kafka.Image,
+		defaultOpts...,
+	)
+	te.addBasicContainer(kafkaCtx)
+	te.makeBackupFile()
+	if err != nil {
+		panic(err)
+	}
+	hostPort, err := kafkaCtx.hostPort(kafkaExternalPort)
+	if err != nil {
+		panic(err)
+	}
+	externalBroker := net.JoinHostPort(te.cli.hostAddress(), hostPort)
+	err = te.cli.copyFiles(kafkaCtx.id(), []containerFile{{path: kafkaExternalFile, content: []byte(externalBroker)}})
+	if err != nil {
+		panic(errors.WithMessage(err, "set kafka external address"))
+	}
+	kafkaCfg.ExternalBrokers = []string{externalBroker}
+	return kafkaCtx, kafkaCfg
+}
+
+// returns address of docker host available from the test process
+func (c *ispDockerClient) hostAddress() string {
+	u, err := url.Parse(c.c.DaemonHost())
+	if err != nil || u.Scheme == "unix" || u.Scheme == "npipe" || u.Hostname() == "" {
+		return "127.0.0.1"
+	}
+	return u.Hostname()
+}
+
+// returns host port bound to container tcp port
+func (ctx *ContainerContext) hostPort(containerPort string) (string, error) {
+	info, err := ctx.client.c.ContainerInspect(context.Background(), ctx.id())
+	if err != nil {
+		return "", errors.Wrap(err, "container inspect")
+	}
+	for _, binding := range info.NetworkSettings.Ports[nat.Port(containerPort+"/tcp")] {
+		if binding.HostPort != "" {
+			return binding.HostPort, nil
+		}
+	}
+	return "", errors.Errorf("port %s of container %s is not bound to host", containerPort, ctx.Name())
+}
+
+func kafkaClusterId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrap(err, "generate kafka cluster id")
+	}
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	// isp-log text format: [time] [LEVEL] [code] [message] [metadata]
+	ispLogLineRegexp  = regexp.MustCompile(`^\[([^\]]*)\] \[([A-Z]+) ?\] \[(-?\d+)\] \[(.*)\] \[(.*)\]$`)
+	ispLogFieldRegexp = regexp.MustCompile(`([^\s=]+)="([^"]*)"`)
+	ansiColorRegexp   = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+	jsonLevelKeys   = []string{"level", "lvl"}
+	jsonMessageKeys = []string{"msg", "message"}
+	jsonCodeKeys    = []string{"code", "$$code"}
+	jsonTimeKeys    = []string{"time", "ts", "timestamp"}
+)
+
+// ParsedLogEntry is a container output line produced by isp-log
+type ParsedLogEntry struct {
+	LogEntry
+	// lower case level: trace, debug, info, warn, error, fatal or panic
+	Level   string
+	Code    int
+	Message string
+	Fields  map[string]interface{}
+}
+
+func (e ParsedLogEntry) IsError() bool {
+	return e.Level == "error" || e.Level == "fatal" || e.Level == "panic"
+}
+
+// ParseLogEntry parses isp-log line in JSON or text format
+// returns false if line is not produced by isp-log
+func ParseLogEntry(entry LogEntry) (ParsedLogEntry, bool) {
+	line := strings.TrimSpace(entry.Line)
+	if strings.HasPrefix(line, "{") {
+		return parseJSONLogEntry(entry, line)
+	}
+	return parseTextLogEntry(entry, ansiColorRegexp.ReplaceAllString(line, ""))
+}
+
+func parseJSONLogEntry(entry LogEntry, line string) (ParsedLogEntry, bool) {
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return ParsedLogEntry{}, false
+	}
+	parsed := ParsedLogEntry{LogEntry: entry, Fields: fields}
+	level, ok := popString(fields, jsonLevelKeys)
+	if !ok {
+		return ParsedLogEntry{}, false
+	}
+	parsed.Level = normalizeLevel(level)
+	parsed.Message, _ = popString(fields, jsonMessageKeys)
+	if code, ok := popString(fields, jsonCodeKeys); ok {
+		parsed.Code, _ = strconv.Atoi(code)
+	}
+	_, _ = popString(fields, jsonTimeKeys)
+	return parsed, true
+}
+
+func parseTextLogEntry(entry LogEntry, line string) (ParsedLogEntry, bool) {
+	match := ispLogLineRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return ParsedLogEntry{}, false
+	}
+	code, _ := strconv.Atoi(match[3])
+	fields := make(map[string]interface{})
+	for _, field := range ispLogFieldRegexp.FindAllStringSubmatch(match[5], -1) {
+		fields[field[1]] = field[2]
+	}
+	return ParsedLogEntry{
+		LogEntry: entry,
+		Level:    normalizeLevel(match[2]),
+		Code:     code,
+		Message:  match[4],
+		Fields:   fields,
+	}, true
+}
+
+func popString(fields map[string]interface{}, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			delete(fields, key)
+			return fmt.Sprint(value), true
+		}
+	}
+	return "", false
+}
+
+func normalizeLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "warning" {
+		return "warn"
+	}
+	return level
+}
+
+type LogMatcher func(entry ParsedLogEntry) bool
+
+func LevelIs(levels ...string) LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		for _, level := range levels {
+			if entry.Level == normalizeLevel(level) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func IsErrorLevel() LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		return entry.IsError()
+	}
+}
+
+func MessageContains(substr string) LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		return strings.Contains(entry.Message, substr)
+	}
+}
+
+func MessageMatches(re *regexp.Regexp) LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		return re.MatchString(entry.Message)
+	}
+}
+
+func CodeIs(code int) LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		return entry.Code == code
+	}
+}
+
+func HasField(key string) LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		_, ok := entry.Fields[key]
+		return ok
+	}
+}
+
+// values are compared by their string representation
+func FieldEquals(key string, value interface{}) LogMatcher {
+	expected := fmt.Sprint(value)
+	return func(entry ParsedLogEntry) bool {
+		actual, ok := entry.Fields[key]
+		return ok && fmt.Sprint(actual) == expected
+	}
+}
+
+func AllOf(matchers ...LogMatcher) LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		for _, m := range matchers {
+			if !m(entry) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func AnyOf(matchers ...LogMatcher) LogMatcher {
+	return func(entry ParsedLogEntry) bool {
+		for _, m := range matchers {
+			if m(entry) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ParsedLogs returns all kept output lines produced by isp-log
+func (ctx *ContainerContext) ParsedLogs() []ParsedLogEntry {
+	return parseLogEntries(ctx.Logs(), nil)
+}
+
+// FindLogs returns all kept isp-log entries matching all matchers
+func (ctx *ContainerContext) FindLogs(matchers ...LogMatcher) []ParsedLogEntry {
+	return parseLogEntries(ctx.Logs(), AllOf(matchers...))
+}
+
+// ExpectLog waits until container logs entry matching matcher
+// entries already kept in memory buffer are checked first
+func (ctx *ContainerContext) ExpectLog(matcher LogMatcher, timeout time.Duration) (ParsedLogEntry, error) {
+	entry, err := ctx.waitLogEntry(func(entry LogEntry) bool {
+		parsed, ok := ParseLogEntry(entry)
+		return ok && matcher(parsed)
+	}, timeout)
+	if err != nil {
+		return ParsedLogEntry{}, err
+	}
+	parsed, _ := ParseLogEntry(entry)
+	return parsed, nil
+}
+
+// AssertNoErrorsLogged fails t if container logged any error, fatal or panic entry
+func (ctx *ContainerContext) AssertNoErrorsLogged(t assert.TestingT) bool {
+	entries := ctx.FindLogs(IsErrorLevel())
+	if len(entries) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("container %s logged %d error(s)", ctx.name, len(entries)), formatLogEntries(entries))
+}
+
+func parseLogEntries(entries []LogEntry, matcher LogMatcher) []ParsedLogEntry {
+	result := make([]ParsedLogEntry, 0)
+	for _, entry := range entries {
+		parsed, ok := ParseLogEntry(entry)
+		if ok && (matcher == nil || matcher(parsed)) {
+			result = append(result, parsed)
+		}
+	}
+	return result
+}
+
+func formatLogEntries(entries []ParsedLogEntry) string {
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, entry.Line)
+	}
+	return strings.Join(lines, "\n")
+}
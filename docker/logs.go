@@ -0,0 +1,381 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultLogBufferSize = 10000
+
+	logTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+type LogStream int
+
+const (
+	Stdout LogStream = iota + 1
+	Stderr
+)
+
+func (s LogStream) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// LogEntry is a single line of container output
+type LogEntry struct {
+	Time   time.Time
+	Stream LogStream
+	Line   string
+}
+
+func (e LogEntry) String() string {
+	return e.Line
+}
+
+type logSink struct {
+	writer     io.Writer
+	filePath   string
+	stream     LogStream // zero value means both streams
+	prefix     string
+	namePrefix bool
+	timestamps bool
+}
+
+func (s logSink) write(entry LogEntry) {
+	if s.stream != 0 && s.stream != entry.Stream {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if s.timestamps {
+		buf.WriteString(entry.Time.Format(logTimeLayout))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(s.prefix)
+	buf.WriteString(entry.Line)
+	buf.WriteByte('\n')
+	_, _ = s.writer.Write(buf.Bytes())
+}
+
+// logCollector demultiplexes container output, keeps last lines in ring buffer
+// and fans them out to all configured sinks and subscribers
+type logCollector struct {
+	mu          sync.Mutex
+	entries     []LogEntry
+	start       int
+	size        int
+	sinks       []logSink
+	closers     []io.Closer
+	subscribers map[int]func(LogEntry)
+	nextSubId   int
+	// time of the latest entry, used to resume following after container restart
+	lastTime time.Time
+}
+
+func newLogCollector(bufferSize int) *logCollector {
+	if bufferSize <= 0 {
+		bufferSize = DefaultLogBufferSize
+	}
+	return &logCollector{
+		entries:     make([]LogEntry, bufferSize),
+		subscribers: make(map[int]func(LogEntry)),
+	}
+}
+
+// open file sinks and resolve container name prefixes
+func (c *logCollector) setSinks(sinks []logSink, containerName string) error {
+	for _, sink := range sinks {
+		if sink.filePath != "" {
+			file, err := os.OpenFile(sink.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return errors.Wrapf(err, "open log file %s", sink.filePath)
+			}
+			sink.writer = file
+			c.closers = append(c.closers, file)
+		}
+		if sink.namePrefix {
+			sink.prefix = fmt.Sprintf("[%s] ", containerName)
+		}
+		c.sinks = append(c.sinks, sink)
+	}
+	return nil
+}
+
+func (c *logCollector) add(entry LogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	capacity := len(c.entries)
+	if c.size < capacity {
+		c.entries[(c.start+c.size)%capacity] = entry
+		c.size++
+	} else {
+		c.entries[c.start] = entry
+		c.start = (c.start + 1) % capacity
+	}
+
+	if entry.Time.After(c.lastTime) {
+		c.lastTime = entry.Time
+	}
+	for _, sink := range c.sinks {
+		sink.write(entry)
+	}
+	for _, f := range c.subscribers {
+		f(entry)
+	}
+}
+
+func (c *logCollector) filter(f func(LogEntry) bool) []LogEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make([]LogEntry, 0)
+	for i := 0; i < c.size; i++ {
+		entry := c.entries[(c.start+i)%len(c.entries)]
+		if f == nil || f(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// subscribe calls f for every new entry, f must not block
+// returns function to unsubscribe
+func (c *logCollector) subscribe(f func(LogEntry)) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.nextSubId
+	c.nextSubId++
+	c.subscribers[id] = f
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers, id)
+	}
+}
+
+func (c *logCollector) latest() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastTime
+}
+
+// entries at or before after are skipped, they were collected before container restart
+func (c *logCollector) follow(reader io.ReadCloser, after time.Time) {
+	defer reader.Close()
+	stdout := &logLineWriter{stream: Stdout, collector: c, after: after}
+	stderr := &logLineWriter{stream: Stderr, collector: c, after: after}
+	_, _ = stdcopy.StdCopy(stdout, stderr, reader)
+	stdout.flush()
+	stderr.flush()
+}
+
+func (c *logCollector) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for _, closer := range c.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = errors.Wrap(closeErr, "close log sink")
+		}
+	}
+	c.closers = nil
+	return err
+}
+
+// splits stream into lines, each line is prefixed by docker timestamp
+type logLineWriter struct {
+	stream    LogStream
+	collector *logCollector
+	after     time.Time
+	buf       []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *logLineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *logLineWriter) emit(line string) {
+	entry := LogEntry{Stream: w.stream, Line: strings.TrimSuffix(line, "\r")}
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			entry.Time = t
+			entry.Line = strings.TrimSuffix(line[i+1:], "\r")
+		}
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	} else if !entry.Time.After(w.after) {
+		return
+	}
+	w.collector.add(entry)
+}
+
+// attach to container output written after specified time, zero time means from the beginning
+// docker api accepts since with nanoseconds, entries with the same timestamp are skipped by collector
+func (ctx *ContainerContext) followLogs(after time.Time) error {
+	since := ""
+	if !after.IsZero() {
+		since = fmt.Sprintf("%d.%09d", after.Unix(), after.Nanosecond())
+	}
+	reader, err := ctx.client.c.ContainerLogs(
+		context.Background(),
+		ctx.id(),
+		types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true, Since: since},
+	)
+	if err != nil {
+		return err
+	}
+	go ctx.logs.follow(reader, after)
+	return nil
+}
+
+// Logs returns container output lines kept in memory buffer
+func (ctx *ContainerContext) Logs() []LogEntry {
+	if ctx.logs == nil {
+		return nil
+	}
+	return ctx.logs.filter(nil)
+}
+
+// LogsSince returns container output lines written after t
+func (ctx *ContainerContext) LogsSince(t time.Time) []LogEntry {
+	if ctx.logs == nil {
+		return nil
+	}
+	return ctx.logs.filter(func(entry LogEntry) bool {
+		return entry.Time.After(t)
+	})
+}
+
+// StreamLogs returns container output lines from the specified stream
+func (ctx *ContainerContext) StreamLogs(stream LogStream) []LogEntry {
+	if ctx.logs == nil {
+		return nil
+	}
+	return ctx.logs.filter(func(entry LogEntry) bool {
+		return entry.Stream == stream
+	})
+}
+
+// GrepLogs returns container output lines matching the regular expression
+func (ctx *ContainerContext) GrepLogs(re *regexp.Regexp) []LogEntry {
+	if ctx.logs == nil {
+		return nil
+	}
+	return ctx.logs.filter(func(entry LogEntry) bool {
+		return re.MatchString(entry.Line)
+	})
+}
+
+// LogsContain reports whether any kept output line contains substr
+func (ctx *ContainerContext) LogsContain(substr string) bool {
+	if ctx.logs == nil {
+		return false
+	}
+	return len(ctx.logs.filter(func(entry LogEntry) bool {
+		return strings.Contains(entry.Line, substr)
+	})) > 0
+}
+
+// WaitLog waits until container writes line matching the regular expression
+// lines already kept in memory buffer are checked first
+func (ctx *ContainerContext) WaitLog(re *regexp.Regexp, timeout time.Duration) (LogEntry, error) {
+	return ctx.waitLogEntry(func(entry LogEntry) bool {
+		return re.MatchString(entry.Line)
+	}, timeout)
+}
+
+func (ctx *ContainerContext) waitLogEntry(match func(LogEntry) bool, timeout time.Duration) (LogEntry, error) {
+	if ctx.logs == nil {
+		return LogEntry{}, errors.New("container logs are not collected")
+	}
+
+	found := make(chan LogEntry, 1)
+	unsubscribe := ctx.logs.subscribe(func(entry LogEntry) {
+		if match(entry) {
+			select {
+			case found <- entry:
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+
+	if entries := ctx.logs.filter(match); len(entries) > 0 {
+		return entries[0], nil
+	}
+
+	select {
+	case entry := <-found:
+		return entry, nil
+	case <-time.After(timeout):
+		return LogEntry{}, errors.Errorf("expected log entry not found in container %s after %s", ctx.name, timeout)
+	}
+}
+
+// subscribe to new container output before triggering some action,
+// unlike waitLogEntry lines already kept in memory buffer are ignored
+// returned wait must be called once, it unsubscribes on return
+func (ctx *ContainerContext) watchLogEntry(match func(LogEntry) bool) func(timeout time.Duration) (LogEntry, error) {
+	if ctx.logs == nil {
+		return func(time.Duration) (LogEntry, error) {
+			return LogEntry{}, errors.New("container logs are not collected")
+		}
+	}
+
+	found := make(chan LogEntry, 1)
+	unsubscribe := ctx.logs.subscribe(func(entry LogEntry) {
+		if match(entry) {
+			select {
+			case found <- entry:
+			default:
+			}
+		}
+	})
+	return func(timeout time.Duration) (LogEntry, error) {
+		defer unsubscribe()
+		select {
+		case entry := <-found:
+			return entry, nil
+		case <-time.After(timeout):
+			return LogEntry{}, errors.Errorf("expected log entry not found in container %s after %s", ctx.name, timeout)
+		}
+	}
+}
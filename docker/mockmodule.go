@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"time"
+
+	"github.com/integration-system/isp-lib-test/mockmodule"
+	"github.com/integration-system/isp-lib/v2/structure"
+)
+
+const mockModuleConnectTimeout = 10 * time.Second
+
+// start mock isp module on the bridge address and register it in config-service at configAddr
+// declare endpoints of module before calling, module is closed on Cleanup
+func (te *TestEnvironment) RunMockModule(module *mockmodule.Module, configAddr structure.AddressConfiguration) *mockmodule.Module {
+	host, err := te.cli.GetBridgeAddress()
+	if err != nil {
+		panic(err)
+	}
+	if err := module.Start(host, configAddr, mockModuleConnectTimeout); err != nil {
+		panic(err)
+	}
+	te.addCloser(module)
+	return module
+}
@@ -6,11 +6,17 @@ import (
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/go-connections/nat"
+	configUtils "github.com/integration-system/isp-lib-test/utils/config"
 	"io"
+	"os"
+	"strings"
 )
 
 type options struct {
-	logger io.Writer
+	logger        io.Writer
+	logSinks      []logSink
+	logTimestamps bool
+	logBufferSize int
 
 	imageName     string
 	pullImage     bool
@@ -26,14 +32,87 @@ type options struct {
 	networkName string
 
 	volume []string
+
+	files      []containerFile
+	entrypoint []string
+	cmd        []string
+	tmpfs      map[string]string
+
+	pg pgOptions
+
+	remoteConfigSchema string
+	publishedSchema    *publishedSchema
+
+	onCreate func(ctx *ContainerContext)
+}
+
+type publishedSchema struct {
+	client     *configUtils.Client
+	moduleName string
 }
 
 type Option func(opts *options)
 
-// redirect docker container runtime logs
+func newOptions(opts []Option) *options {
+	ops := &options{}
+	for _, v := range opts {
+		v(ops)
+	}
+	return ops
+}
+
+// redirect docker container runtime logs, stdout and stderr are combined
 func WithLogger(logger io.Writer) Option {
 	return func(opts *options) {
 		opts.logger = logger
+		opts.logSinks = append(opts.logSinks, logSink{writer: logger})
+	}
+}
+
+// redirect only container stdout
+func WithStdoutLogger(logger io.Writer) Option {
+	return func(opts *options) {
+		opts.logSinks = append(opts.logSinks, logSink{writer: logger, stream: Stdout})
+	}
+}
+
+// redirect only container stderr
+func WithStderrLogger(logger io.Writer) Option {
+	return func(opts *options) {
+		opts.logSinks = append(opts.logSinks, logSink{writer: logger, stream: Stderr})
+	}
+}
+
+// redirect container logs, each line is prefixed by container name
+func WithPrefixedLogger(logger io.Writer) Option {
+	return func(opts *options) {
+		opts.logSinks = append(opts.logSinks, logSink{writer: logger, namePrefix: true})
+	}
+}
+
+// redirect container logs to stdout, each line is prefixed by container name
+func WithConsoleLogger() Option {
+	return WithPrefixedLogger(os.Stdout)
+}
+
+// append container logs to file, file is created if not exists
+func WithFileLogger(path string) Option {
+	return func(opts *options) {
+		opts.logSinks = append(opts.logSinks, logSink{filePath: path})
+	}
+}
+
+// prefix each line in all log sinks with docker timestamp
+func WithLogTimestamps() Option {
+	return func(opts *options) {
+		opts.logTimestamps = true
+	}
+}
+
+// set number of last log lines kept in memory, DefaultLogBufferSize by default
+func WithLogBufferSize(lines int) Option {
+	return func(opts *options) {
+		opts.logBufferSize = lines
 	}
 }
 
@@ -76,6 +155,27 @@ func WithEnv(vars map[string]string) Option {
 	}
 }
 
+// add environment variables to ones set by previous options, variables with the same name are overridden
+// use it to extend variables required by infrastructure containers, WithEnv replaces them
+func AddEnv(vars map[string]string) Option {
+	return func(opts *options) {
+		for k, v := range vars {
+			variable := fmt.Sprintf("%s=%s", k, v)
+			replaced := false
+			for i := range opts.env {
+				if strings.HasPrefix(opts.env[i], k+"=") {
+					opts.env[i] = variable
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				opts.env = append(opts.env, variable)
+			}
+		}
+	}
+}
+
 // set container name and hostname in docker network by default
 func WithName(containerName string) Option {
 	return func(opts *options) {
@@ -107,3 +207,68 @@ func WithCustomImage(image string) Option {
 		opts.imageName = image
 	}
 }
+
+// copy file with content into container before start, parent directories are created
+func WithFile(containerPath string, content []byte, mode os.FileMode) Option {
+	return func(opts *options) {
+		opts.files = append(opts.files, containerFile{path: containerPath, content: content, mode: mode})
+	}
+}
+
+// render text/template tmpl with data and copy result into container before start
+func WithTemplateFile(containerPath string, tmpl string, data interface{}, mode os.FileMode) Option {
+	return func(opts *options) {
+		opts.files = append(opts.files, containerFile{path: containerPath, template: tmpl, data: data, mode: mode})
+	}
+}
+
+// validate remoteConfig of RunAppContainer against json schema file before container is created
+// use WithPublishedRemoteConfigSchema to validate against schema published by module
+func WithRemoteConfigSchema(schemaPath string) Option {
+	return func(opts *options) {
+		opts.remoteConfigSchema = schemaPath
+	}
+}
+
+// validate remoteConfig of RunAppContainer against schema published by module to config-service
+// if schema is already published, e.g. by another replica, config is validated before container is created,
+// otherwise TestEnvironment.RunAppContainer waits until started module publishes schema
+// and fails the current test with field-level errors
+func WithPublishedRemoteConfigSchema(cli *configUtils.Client, moduleName string) Option {
+	return func(opts *options) {
+		opts.publishedSchema = &publishedSchema{client: cli, moduleName: moduleName}
+	}
+}
+
+// override image default command
+func WithCmd(cmd ...string) Option {
+	return func(opts *options) {
+		opts.cmd = cmd
+	}
+}
+
+// override image default entrypoint, image default command is not used if entrypoint is set
+func WithEntrypoint(entrypoint ...string) Option {
+	return func(opts *options) {
+		opts.entrypoint = entrypoint
+	}
+}
+
+// call f right after container is created and before it is started
+func withCreateHook(f func(ctx *ContainerContext)) Option {
+	return func(opts *options) {
+		opts.onCreate = f
+	}
+}
+
+// mount tmpfs container_path -> mount options, e.g. "rw,size=256m"
+func WithTmpfs(mounts map[string]string) Option {
+	return func(opts *options) {
+		if opts.tmpfs == nil {
+			opts.tmpfs = make(map[string]string, len(mounts))
+		}
+		for path, mountOpts := range mounts {
+			opts.tmpfs[path] = mountOpts
+		}
+	}
+}
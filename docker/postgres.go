@@ -0,0 +1,156 @@
+package docker
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPGInitTimeout = 2 * time.Minute
+
+	pgInitDir = "/docker-entrypoint-initdb.d"
+	pgDataDir = "/var/lib/postgresql/data"
+)
+
+var (
+	pgInitCompleteRegexp = regexp.MustCompile(`PostgreSQL init process complete`)
+	pgReadyRegexp        = regexp.MustCompile(`database system is ready to accept connections`)
+)
+
+type pgInitScript struct {
+	name    string
+	content []byte
+}
+
+type pgOptions struct {
+	initScripts []pgInitScript
+	extensions  []string
+	settings    map[string]string
+	fast        bool
+	initTimeout time.Duration
+}
+
+// returns true if container has to be waited until initialization is finished
+func (o pgOptions) hasInit() bool {
+	return len(o.initScripts) > 0 || len(o.extensions) > 0
+}
+
+// cmd is a command set by WithCmd, server settings are appended to it
+func (o pgOptions) containerOptions(cmd []string) []Option {
+	opts := make([]Option, 0)
+	for _, script := range o.initScripts {
+		mode := defaultFileMode
+		if strings.HasSuffix(script.name, ".sh") {
+			mode = 0755
+		}
+		opts = append(opts, WithFile(path.Join(pgInitDir, script.name), script.content, mode))
+	}
+
+	settings := make(map[string]string)
+	if o.fast {
+		settings["fsync"] = "off"
+		settings["synchronous_commit"] = "off"
+		settings["full_page_writes"] = "off"
+		opts = append(opts, WithTmpfs(map[string]string{pgDataDir: "rw"}))
+	}
+	for k, v := range o.settings {
+		settings[k] = v
+	}
+	if len(settings) > 0 {
+		keys := make([]string, 0, len(settings))
+		for k := range settings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(cmd) > 0 {
+			cmd = append([]string(nil), cmd...)
+		} else {
+			cmd = []string{"postgres"}
+		}
+		for _, k := range keys {
+			cmd = append(cmd, "-c", fmt.Sprintf("%s=%s", k, settings[k]))
+		}
+		opts = append(opts, WithCmd(cmd...))
+	}
+	return opts
+}
+
+// place sql or shell script into /docker-entrypoint-initdb.d, scripts are executed in name order
+func WithPGInitScript(name string, content []byte) Option {
+	return func(opts *options) {
+		opts.pg.initScripts = append(opts.pg.initScripts, pgInitScript{name: name, content: content})
+	}
+}
+
+// create extensions in database after server is started and init scripts are executed
+// extensions are created by TestEnvironment.RunPGContainer
+func WithPGExtensions(extensions ...string) Option {
+	return func(opts *options) {
+		opts.pg.extensions = append(opts.pg.extensions, extensions...)
+	}
+}
+
+// pass server settings as `postgres -c key=value`
+// if WithCmd is set, settings are appended to its command
+func WithPGSettings(settings map[string]string) Option {
+	return func(opts *options) {
+		if opts.pg.settings == nil {
+			opts.pg.settings = make(map[string]string, len(settings))
+		}
+		for k, v := range settings {
+			opts.pg.settings[k] = v
+		}
+	}
+}
+
+// disable fsync, synchronous_commit and full_page_writes, place data directory on tmpfs
+// never use it for data you want to keep
+func WithPGFastProfile() Option {
+	return func(opts *options) {
+		opts.pg.fast = true
+	}
+}
+
+// set how long TestEnvironment.RunPGContainer waits for init scripts, DefaultPGInitTimeout by default
+func WithPGInitTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.pg.initTimeout = timeout
+	}
+}
+
+// wait until postgres entrypoint finishes init scripts and restarts server
+func (ctx *ContainerContext) waitPGInit(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	complete, err := ctx.WaitLog(pgInitCompleteRegexp, timeout)
+	if err != nil {
+		return errors.WithMessage(err, "wait postgres init scripts")
+	}
+	_, err = ctx.waitLogEntry(func(entry LogEntry) bool {
+		return !entry.Time.Before(complete.Time) && pgReadyRegexp.MatchString(entry.Line)
+	}, time.Until(deadline))
+	if err != nil {
+		return errors.WithMessage(err, "wait postgres start after init")
+	}
+	return nil
+}
+
+// create extensions with psql inside container, server must be ready to accept connections
+func (ctx *ContainerContext) createPGExtensions(dbAndUserName string, extensions []string) error {
+	for _, ext := range extensions {
+		query := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS \"%s\";", strings.ReplaceAll(ext, `"`, `""`))
+		code, output, err := ctx.Exec("psql", "-v", "ON_ERROR_STOP=1", "-U", dbAndUserName, "-d", dbAndUserName, "-c", query)
+		if err != nil {
+			return errors.WithMessagef(err, "create extension %s", ext)
+		}
+		if code != 0 {
+			return errors.Errorf("create extension %s: psql exited with code %d: %s", ext, code, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/integration-system/isp-lib-test/utils/config"
+	"github.com/pkg/errors"
+)
+
+// isp modules log this message after new remote config is applied
+var RemoteConfigAppliedLogRegexp = regexp.MustCompile(`remote config applied`)
+
+const (
+	publishedSchemaTimeout      = time.Minute
+	publishedSchemaPollInterval = 200 * time.Millisecond
+)
+
+// returns schema published by module, nil if module has not published it yet
+func (s *publishedSchema) load() (map[string]interface{}, error) {
+	module, err := s.client.Module(s.moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if module == nil {
+		return nil, nil
+	}
+	schema, err := s.client.Schema(module.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(schema.Schema) == 0 {
+		return nil, nil
+	}
+	return schema.Schema, nil
+}
+
+// waits until started module publishes schema and validates remote config against it
+func (s *publishedSchema) validateAfterStart(remoteConfig interface{}, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		schema, err := s.load()
+		if err != nil {
+			return err
+		}
+		if schema != nil {
+			return errors.WithMessagef(config.ValidateConfig(schema, remoteConfig), "module %s", s.moduleName)
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("module %s has not published remote config schema after %s", s.moduleName, timeout)
+		}
+		time.Sleep(publishedSchemaPollInterval)
+	}
+}
+
+// fails the current test, prints err if no test is running
+func (te *TestEnvironment) reportError(err error) {
+	te.crashMu.Lock()
+	defer te.crashMu.Unlock()
+	if te.currentTest != nil {
+		te.currentTest.Errorf("%v", err)
+		return
+	}
+	fmt.Printf("%v\n", err)
+}
+
+// UpdateRemoteConfig merges patch into active remote config of module through config-service
+// and waits until every app container logs that new config is applied
+// if apps are empty, containers of this environment connected to config-service as the module are used,
+// update fails if there is no such container as acknowledgement can't be received
+// original config is restored at the end of t, restore waits for acknowledgement too
+func (te *TestEnvironment) UpdateRemoteConfig(
+	t testing.TB,
+	configCli *config.Client,
+	moduleName string,
+	patch map[string]interface{},
+	timeout time.Duration,
+	apps ...*ContainerContext,
+) *config.ModuleConfig {
+	t.Helper()
+	if len(apps) == 0 {
+		var err error
+		apps, err = te.moduleContainers(configCli, moduleName)
+		if err != nil {
+			t.Fatalf("update remote config of %s: %v", moduleName, err)
+		}
+	}
+	var update *config.ConfigUpdate
+	err := awaitRemoteConfigApplied(apps, timeout, func() error {
+		var err error
+		update, err = configCli.PatchActiveConfig(moduleName, patch)
+		return err
+	})
+	if update != nil {
+		t.Cleanup(func() {
+			err := awaitRemoteConfigApplied(apps, timeout, update.Restore)
+			if err != nil {
+				t.Errorf("restore remote config of %s: %v", moduleName, err)
+			}
+		})
+	}
+	if err != nil {
+		t.Fatalf("update remote config of %s: %v", moduleName, err)
+	}
+	return &update.Updated
+}
+
+// finds app containers by addresses of module connections to config-service
+func (te *TestEnvironment) moduleContainers(configCli *config.Client, moduleName string) ([]*ContainerContext, error) {
+	module, err := configCli.Module(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if module == nil || !module.Connected() {
+		return nil, errors.Errorf("module %s is not connected to config service", moduleName)
+	}
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	result := make([]*ContainerContext, 0, len(module.Status))
+	for _, conn := range module.Status {
+		for _, app := range te.appContainers {
+			if conn.Address.IP == app.Name() || conn.Address.IP == app.GetIPAddress() {
+				result = append(result, app)
+				break
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.Errorf("no app containers of module %s found in test environment, pass them explicitly", moduleName)
+	}
+	return result, nil
+}
+
+// runs action and waits until each app logs applying of remote config
+func awaitRemoteConfigApplied(apps []*ContainerContext, timeout time.Duration, action func() error) error {
+	if len(apps) == 0 {
+		return errors.New("no app containers to acknowledge remote config")
+	}
+	waits := make([]func(time.Duration) (LogEntry, error), len(apps))
+	for i, app := range apps {
+		waits[i] = app.watchLogEntry(func(entry LogEntry) bool {
+			return RemoteConfigAppliedLogRegexp.MatchString(entry.Line)
+		})
+	}
+	err := action()
+	deadline := time.Now().Add(timeout)
+	for i, wait := range waits {
+		if err != nil {
+			// unsubscribe only
+			_, _ = wait(0)
+			continue
+		}
+		if _, waitErr := wait(time.Until(deadline)); waitErr != nil {
+			err = errors.Wrapf(waitErr, "remote config is not applied by %s", apps[i].Name())
+		}
+	}
+	return err
+}
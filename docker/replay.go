@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"github.com/integration-system/isp-lib-test/replay"
+	"github.com/integration-system/isp-lib/v2/structure"
+)
+
+// start recording proxy for isp dependency module on the bridge address
+// records calls to upstream into testdata/cassettes/<name>.json or replays them if suite runs with -replay flag,
+// in replay mode upstream is not called and dependency container need not be run
+// pass Address of proxy to module under test or use RegisterModule to route calls through config-service,
+// proxy is closed on Cleanup
+func (te *TestEnvironment) RunGrpcRecorder(name string, upstream structure.AddressConfiguration) *replay.GrpcProxy {
+	host, err := te.cli.GetBridgeAddress()
+	if err != nil {
+		panic(err)
+	}
+	cassette, mode := te.cassette(name)
+	proxy, err := replay.NewGrpcProxy(host, upstream, cassette, mode)
+	if err != nil {
+		panic(err)
+	}
+	te.addCloser(proxy)
+	return proxy
+}
+
+// start recording proxy for third-party HTTP API on the bridge address
+// records requests to upstreamUrl into testdata/cassettes/<name>.json or replays them if suite runs with -replay flag
+// pass URL of proxy to module under test, proxy is closed on Cleanup
+func (te *TestEnvironment) RunHttpRecorder(name string, upstreamUrl string) *replay.HttpProxy {
+	host, err := te.cli.GetBridgeAddress()
+	if err != nil {
+		panic(err)
+	}
+	cassette, mode := te.cassette(name)
+	proxy, err := replay.NewHttpProxy(host, upstreamUrl, cassette, mode)
+	if err != nil {
+		panic(err)
+	}
+	te.addCloser(proxy)
+	return proxy
+}
+
+func (te *TestEnvironment) cassette(name string) (*replay.Cassette, replay.Mode) {
+	path := te.testCtx.GetCassettePath(name)
+	if !te.testCtx.ReplayMode() {
+		return replay.NewCassette(path), replay.ModeRecord
+	}
+	cassette, err := replay.LoadCassette(path)
+	if err != nil {
+		panic(err)
+	}
+	return cassette, replay.ModeReplay
+}
@@ -0,0 +1,185 @@
+package docker
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReplicaConfigFunc produces local configuration of i-th replica,
+// use TestContext.GetModuleReplicaLocalConfig to get unique outer address
+type ReplicaConfigFunc func(replica int) interface{}
+
+// ReplicaLogEntry is a container output line with the name of replica which wrote it
+type ReplicaLogEntry struct {
+	Replica string
+	LogEntry
+}
+
+func (e ReplicaLogEntry) String() string {
+	return e.Replica + ": " + e.LogEntry.String()
+}
+
+// ReplicaSet is a group of containers running the same module
+type ReplicaSet struct {
+	te            *TestEnvironment
+	image         string
+	localConfigFn ReplicaConfigFunc
+	remoteConfig  interface{}
+	opts          []Option
+
+	mu       sync.Mutex
+	replicas []*ContainerContext
+	// index of the next replica, never decreases to keep container names unique
+	next int
+}
+
+// run n instances of isp application
+// if local config of replica implements ContainerName() (as ctx.DefaultLocalConfiguration does)
+// container is named after it, so replicas are reachable by their outer addresses
+func (te *TestEnvironment) RunAppReplicas(image string, n int, localConfigFn ReplicaConfigFunc, remoteConfig interface{}, opts ...Option) *ReplicaSet {
+	rs := &ReplicaSet{
+		te:            te,
+		image:         image,
+		localConfigFn: localConfigFn,
+		remoteConfig:  remoteConfig,
+		opts:          opts,
+	}
+	if err := rs.ScaleUp(n); err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+// Replicas returns running replicas in order of creation
+func (rs *ReplicaSet) Replicas() []*ContainerContext {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]*ContainerContext(nil), rs.replicas...)
+}
+
+func (rs *ReplicaSet) Replica(i int) *ContainerContext {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.replicas[i]
+}
+
+func (rs *ReplicaSet) Len() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return len(rs.replicas)
+}
+
+// Scale starts or removes replicas to get exactly n running
+func (rs *ReplicaSet) Scale(n int) error {
+	if n < 0 {
+		return errors.Errorf("invalid replicas count %d", n)
+	}
+	current := rs.Len()
+	if n > current {
+		return rs.ScaleUp(n - current)
+	}
+	return rs.ScaleDown(current - n)
+}
+
+// ScaleUp starts k new replicas
+func (rs *ReplicaSet) ScaleUp(k int) error {
+	for i := 0; i < k; i++ {
+		if err := rs.startReplica(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScaleDown removes k most recently started replicas
+func (rs *ReplicaSet) ScaleDown(k int) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if k > len(rs.replicas) {
+		return errors.Errorf("unable to remove %d of %d replicas", k, len(rs.replicas))
+	}
+	for i := 0; i < k; i++ {
+		last := len(rs.replicas) - 1
+		replica := rs.replicas[last]
+		rs.replicas = rs.replicas[:last]
+		// image is shared, remove it with the last replica only
+		if replica.imageId != "" && len(rs.replicas) > 0 {
+			rs.replicas[0].imageId = replica.imageId
+			replica.imageId = ""
+		}
+		rs.te.removeAppContainer(replica)
+		if err := replica.Close(); err != nil {
+			return errors.WithMessagef(err, "remove replica %s", replica.Name())
+		}
+	}
+	rs.te.makeBackupFile()
+	return nil
+}
+
+func (rs *ReplicaSet) startReplica() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	localConfig := rs.localConfigFn(rs.next)
+	rs.next++
+	opts := []Option{WithNetwork(rs.te.network)}
+	if named, ok := localConfig.(interface{ ContainerName() string }); ok {
+		opts = append(opts, WithName(named.ContainerName()))
+	}
+	opts = append(opts, rs.opts...)
+	registered := false
+	opts = append(opts, withCreateHook(func(ctx *ContainerContext) {
+		rs.te.registerAppContainer(ctx)
+		registered = true
+	}))
+	replica, err := rs.te.cli.RunAppContainer(rs.image, localConfig, rs.remoteConfig, opts...)
+	if !registered {
+		rs.te.registerAppContainer(replica)
+	}
+	if err != nil {
+		return errors.WithMessage(err, "run replica")
+	}
+	if len(rs.replicas) > 0 {
+		replica.imageId = ""
+	}
+	rs.replicas = append(rs.replicas, replica)
+	return nil
+}
+
+// Logs returns output lines of all replicas ordered by time
+func (rs *ReplicaSet) Logs() []ReplicaLogEntry {
+	return rs.collectLogs(func(replica *ContainerContext) []LogEntry {
+		return replica.Logs()
+	})
+}
+
+// LogsSince returns output lines of all replicas written after t ordered by time
+func (rs *ReplicaSet) LogsSince(t time.Time) []ReplicaLogEntry {
+	return rs.collectLogs(func(replica *ContainerContext) []LogEntry {
+		return replica.LogsSince(t)
+	})
+}
+
+// GrepLogs returns output lines of all replicas matching the regular expression ordered by time
+func (rs *ReplicaSet) GrepLogs(re *regexp.Regexp) []ReplicaLogEntry {
+	return rs.collectLogs(func(replica *ContainerContext) []LogEntry {
+		return replica.GrepLogs(re)
+	})
+}
+
+func (rs *ReplicaSet) collectLogs(logs func(replica *ContainerContext) []LogEntry) []ReplicaLogEntry {
+	result := make([]ReplicaLogEntry, 0)
+	for _, replica := range rs.Replicas() {
+		for _, entry := range logs(replica) {
+			result = append(result, ReplicaLogEntry{Replica: replica.Name(), LogEntry: entry})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+type WaitCondition = container.WaitCondition
+
+const (
+	// wait for any of the non-running states: created, exited, dead, removing or removed
+	WaitNotRunning = container.WaitConditionNotRunning
+	// wait for the next time the container changes state to a non-running one
+	WaitNextExit = container.WaitConditionNextExit
+	// wait for the container to be removed
+	WaitRemoved = container.WaitConditionRemoved
+)
+
+type ContainerState struct {
+	// one of created, running, paused, restarting, removing, exited or dead
+	Status       string
+	Running      bool
+	Paused       bool
+	Restarting   bool
+	OOMKilled    bool
+	Dead         bool
+	ExitCode     int
+	Error        string
+	RestartCount int
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	// one of starting, healthy or unhealthy, empty if container has no healthcheck
+	Health string
+}
+
+func (s ContainerState) Exited() bool {
+	return s.Status == "exited" || s.Status == "dead"
+}
+
+// State inspects the container and returns its current state
+func (ctx *ContainerContext) State() (*ContainerState, error) {
+	if ctx.id() == "" {
+		return nil, errors.New("container is not created")
+	}
+	info, err := ctx.client.c.ContainerInspect(context.Background(), ctx.id())
+	if err != nil {
+		return nil, errors.Wrap(err, "container inspect")
+	}
+	if info.ContainerJSONBase == nil || info.State == nil {
+		return nil, errors.New("container inspect: empty state")
+	}
+
+	s := info.State
+	state := &ContainerState{
+		Status:       s.Status,
+		Running:      s.Running,
+		Paused:       s.Paused,
+		Restarting:   s.Restarting,
+		OOMKilled:    s.OOMKilled,
+		Dead:         s.Dead,
+		ExitCode:     s.ExitCode,
+		Error:        s.Error,
+		RestartCount: info.RestartCount,
+		StartedAt:    parseDockerTime(s.StartedAt),
+		FinishedAt:   parseDockerTime(s.FinishedAt),
+	}
+	if s.Health != nil {
+		state.Health = s.Health.Status
+	}
+	return state, nil
+}
+
+// Wait blocks until the container reaches the condition or waitCtx is done
+// returns the container exit code
+func (ctx *ContainerContext) Wait(waitCtx context.Context, condition WaitCondition) (int, error) {
+	if ctx.id() == "" {
+		return 0, errors.New("container is not created")
+	}
+	respCh, errCh := ctx.client.c.ContainerWait(waitCtx, ctx.id(), condition)
+	select {
+	case resp := <-respCh:
+		if resp.Error != nil {
+			return int(resp.StatusCode), errors.Errorf("container wait: %s", resp.Error.Message)
+		}
+		return int(resp.StatusCode), nil
+	case err := <-errCh:
+		return 0, errors.Wrap(err, "container wait")
+	}
+}
+
+func parseDockerTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,275 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const DefaultStatsInterval = 5 * time.Second
+
+// StatsSample is a single resource usage measurement of container
+type StatsSample struct {
+	Time            time.Time
+	CPUPercent      float64
+	MemoryBytes     float64
+	MemoryLimit     float64
+	NetworkRxBytes  float64
+	NetworkTxBytes  float64
+	BlockReadBytes  float64
+	BlockWriteBytes float64
+}
+
+type StatsMetric struct {
+	Peak    float64
+	Average float64
+	Final   float64
+}
+
+type ContainerStatsSummary struct {
+	Container       string
+	Samples         int
+	CPUPercent      StatsMetric
+	MemoryBytes     StatsMetric
+	NetworkRxBytes  StatsMetric
+	NetworkTxBytes  StatsMetric
+	BlockReadBytes  StatsMetric
+	BlockWriteBytes StatsMetric
+}
+
+func (s ContainerStatsSummary) String() string {
+	return fmt.Sprintf("%s: samples=%d cpu(peak/avg/final)=%.1f%%/%.1f%%/%.1f%% mem=%s/%s/%s net rx/tx=%s/%s block read/write=%s/%s",
+		s.Container, s.Samples,
+		s.CPUPercent.Peak, s.CPUPercent.Average, s.CPUPercent.Final,
+		formatBytes(s.MemoryBytes.Peak), formatBytes(s.MemoryBytes.Average), formatBytes(s.MemoryBytes.Final),
+		formatBytes(s.NetworkRxBytes.Final), formatBytes(s.NetworkTxBytes.Final),
+		formatBytes(s.BlockReadBytes.Final), formatBytes(s.BlockWriteBytes.Final),
+	)
+}
+
+type statsSampler struct {
+	mu      sync.Mutex
+	samples map[*ContainerContext][]StatsSample
+	// containers in order of first sample, removed containers are kept
+	sampled []*ContainerContext
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// EnableStats starts polling resource usage of every environment container with specified interval
+// summary is printed on Cleanup, see also StatsSummary and AssertMemoryBelow
+func (te *TestEnvironment) EnableStats(interval time.Duration) {
+	if te.stats != nil {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultStatsInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	te.stats = &statsSampler{
+		samples: make(map[*ContainerContext][]StatsSample),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go te.sampleStats(ctx, interval)
+}
+
+// StatsSamples returns all recorded samples of container, samples are kept after container is removed
+func (te *TestEnvironment) StatsSamples(container *ContainerContext) []StatsSample {
+	if te.stats == nil {
+		return nil
+	}
+	te.stats.mu.Lock()
+	defer te.stats.mu.Unlock()
+	return append([]StatsSample(nil), te.stats.samples[container]...)
+}
+
+// StatsSummary returns peak, average and final values of recorded samples for each container,
+// including containers removed after they were sampled, e.g. by ReplicaSet.ScaleDown
+func (te *TestEnvironment) StatsSummary() []ContainerStatsSummary {
+	result := make([]ContainerStatsSummary, 0)
+	if te.stats == nil {
+		return result
+	}
+	te.stats.mu.Lock()
+	sampled := append([]*ContainerContext(nil), te.stats.sampled...)
+	te.stats.mu.Unlock()
+	for _, container := range sampled {
+		result = append(result, summarizeStats(container.Name(), te.StatsSamples(container)))
+	}
+	return result
+}
+
+// AssertMemoryBelow fails t if peak memory usage of container reached limit
+func (te *TestEnvironment) AssertMemoryBelow(t assert.TestingT, container *ContainerContext, limit uint64) bool {
+	samples := te.StatsSamples(container)
+	if len(samples) == 0 {
+		return assert.Fail(t, fmt.Sprintf("no stats samples recorded for container %s", container.Name()))
+	}
+	summary := summarizeStats(container.Name(), samples)
+	return assert.Less(t, summary.MemoryBytes.Peak, float64(limit),
+		"container %s peak memory usage %s exceeds %s", container.Name(),
+		formatBytes(summary.MemoryBytes.Peak), formatBytes(float64(limit)))
+}
+
+// AssertCPUBelow fails t if average cpu usage of container reached limit in percents
+func (te *TestEnvironment) AssertCPUBelow(t assert.TestingT, container *ContainerContext, percent float64) bool {
+	samples := te.StatsSamples(container)
+	if len(samples) == 0 {
+		return assert.Fail(t, fmt.Sprintf("no stats samples recorded for container %s", container.Name()))
+	}
+	summary := summarizeStats(container.Name(), samples)
+	return assert.Less(t, summary.CPUPercent.Average, percent,
+		"container %s average cpu usage %.1f%% exceeds %.1f%%", container.Name(), summary.CPUPercent.Average, percent)
+}
+
+func (te *TestEnvironment) stopStats() {
+	if te.stats == nil {
+		return
+	}
+	te.stats.cancel()
+	<-te.stats.done
+	summary := te.StatsSummary()
+	if len(summary) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(summary))
+	for _, s := range summary {
+		lines = append(lines, s.String())
+	}
+	fmt.Printf("containers resource usage:\n%s\n", strings.Join(lines, "\n"))
+}
+
+func (te *TestEnvironment) sampleStats(ctx context.Context, interval time.Duration) {
+	defer close(te.stats.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		wg := sync.WaitGroup{}
+		for _, container := range te.containers() {
+			if container.id() == "" {
+				continue
+			}
+			wg.Add(1)
+			go func(container *ContainerContext) {
+				defer wg.Done()
+				sample, err := container.statsSample(ctx)
+				if err != nil {
+					return
+				}
+				te.stats.mu.Lock()
+				if _, ok := te.stats.samples[container]; !ok {
+					te.stats.sampled = append(te.stats.sampled, container)
+				}
+				te.stats.samples[container] = append(te.stats.samples[container], sample)
+				te.stats.mu.Unlock()
+			}(container)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ctx *ContainerContext) statsSample(requestCtx context.Context) (StatsSample, error) {
+	resp, err := ctx.client.c.ContainerStats(requestCtx, ctx.id(), false)
+	if err != nil {
+		return StatsSample{}, errors.Wrap(err, "container stats")
+	}
+	defer resp.Body.Close()
+
+	stats := types.StatsJSON{}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return StatsSample{}, errors.Wrap(err, "decode container stats")
+	}
+	if stats.Read.IsZero() {
+		return StatsSample{}, errors.New("container is not running")
+	}
+
+	sample := StatsSample{
+		Time:        stats.Read,
+		CPUPercent:  cpuPercent(stats),
+		MemoryBytes: float64(stats.MemoryStats.Usage),
+		MemoryLimit: float64(stats.MemoryStats.Limit),
+	}
+	// page cache is not a memory leak, exclude it as docker stats does
+	if cache, ok := stats.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryStats.Usage {
+		sample.MemoryBytes = float64(stats.MemoryStats.Usage - cache)
+	} else if cache, ok := stats.MemoryStats.Stats["cache"]; ok && cache < stats.MemoryStats.Usage {
+		sample.MemoryBytes = float64(stats.MemoryStats.Usage - cache)
+	}
+	for _, network := range stats.Networks {
+		sample.NetworkRxBytes += float64(network.RxBytes)
+		sample.NetworkTxBytes += float64(network.TxBytes)
+	}
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			sample.BlockReadBytes += float64(entry.Value)
+		case "write":
+			sample.BlockWriteBytes += float64(entry.Value)
+		}
+	}
+	return sample, nil
+}
+
+func cpuPercent(stats types.StatsJSON) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+func summarizeStats(container string, samples []StatsSample) ContainerStatsSummary {
+	metric := func(value func(StatsSample) float64) StatsMetric {
+		m := StatsMetric{}
+		sum := 0.0
+		for _, s := range samples {
+			v := value(s)
+			if v > m.Peak {
+				m.Peak = v
+			}
+			sum += v
+		}
+		m.Average = sum / float64(len(samples))
+		m.Final = value(samples[len(samples)-1])
+		return m
+	}
+	return ContainerStatsSummary{
+		Container:       container,
+		Samples:         len(samples),
+		CPUPercent:      metric(func(s StatsSample) float64 { return s.CPUPercent }),
+		MemoryBytes:     metric(func(s StatsSample) float64 { return s.MemoryBytes }),
+		NetworkRxBytes:  metric(func(s StatsSample) float64 { return s.NetworkRxBytes }),
+		NetworkTxBytes:  metric(func(s StatsSample) float64 { return s.NetworkTxBytes }),
+		BlockReadBytes:  metric(func(s StatsSample) float64 { return s.BlockReadBytes }),
+		BlockWriteBytes: metric(func(s StatsSample) float64 { return s.BlockWriteBytes }),
+	}
+}
+
+func formatBytes(value float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
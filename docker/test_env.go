@@ -1,10 +1,15 @@
 package docker
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
+	"regexp"
 	"sync"
+	"testing"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -23,21 +28,84 @@ type TestEnvironment struct {
 	cleanupFlag     bool
 	mu              *sync.Mutex
 	backup          *backup
+
+	failOnLoggedErrors bool
+
+	cancelEvents context.CancelFunc
+	crashMu      sync.Mutex
+	crashPolicy  CrashPolicy
+	crashes      []*CrashError
+	currentTest  testing.TB
+
+	diagnosticsDir string
+	stats          *statsSampler
+
+	// in-process servers started by environment, closed on Cleanup
+	closers []io.Closer
 }
 
 func (te *TestEnvironment) Network() *NetworkContext {
 	return te.network
 }
 
+// FailOnLoggedErrors enables environment-wide mode:
+// test started by StartTest fails if any app container logs error, fatal or panic entry during the test
+func (te *TestEnvironment) FailOnLoggedErrors() {
+	te.failOnLoggedErrors = true
+}
+
+// StartTest binds t to environment checks, call it at the beginning of each test
+// the test fails immediately if some container has already crashed
+func (te *TestEnvironment) StartTest(t testing.TB) {
+	start := time.Now()
+	te.crashMu.Lock()
+	te.currentTest = t
+	policy := te.crashPolicy
+	te.crashMu.Unlock()
+
+	t.Cleanup(func() {
+		te.crashMu.Lock()
+		te.currentTest = nil
+		te.crashMu.Unlock()
+		if te.failOnLoggedErrors {
+			te.checkLoggedErrors(t, start)
+		}
+		if t.Failed() {
+			te.collectDiagnosticsIfEnabled(t.Name())
+		}
+	})
+
+	if err := te.CrashErr(); err != nil && policy == CrashFailTest {
+		t.Fatalf("test environment is broken: %v", err)
+	}
+}
+
+func (te *TestEnvironment) checkLoggedErrors(t testing.TB, since time.Time) {
+	for _, container := range te.applications() {
+		entries := parseLogEntries(container.LogsSince(since), IsErrorLevel())
+		if len(entries) > 0 {
+			t.Errorf("container %s logged %d error(s) during the test:\n%s",
+				container.Name(), len(entries), formatLogEntries(entries))
+		}
+	}
+}
+
 func (te *TestEnvironment) Cleanup() error {
-	te.mu.Lock()
-	defer te.mu.Unlock()
-	if te.cleanupFlag {
+	if !te.markCleanup() {
 		return nil
 	}
-	te.cleanupFlag = true
+	te.cancelEvents()
+	te.stopStats()
+	te.collectDiagnosticsIfEnabled("suite")
+
+	te.mu.Lock()
+	defer te.mu.Unlock()
 
 	var errors *multierror.Error
+	for i := len(te.closers) - 1; i >= 0; i-- {
+		err := te.closers[i].Close()
+		errors = multierror.Append(errors, err)
+	}
 	for i := len(te.appContainers) - 1; i >= 0; i-- {
 		container := te.appContainers[i]
 		err := container.Close()
@@ -61,17 +129,39 @@ func (te *TestEnvironment) RunAppContainer(image string, localConfig interface{}
 		WithNetwork(te.network),
 	}
 	defaultOpts = append(defaultOpts, opts...)
+	var pendingSchema *publishedSchema
+	if ps := newOptions(defaultOpts).publishedSchema; ps != nil && remoteConfig != nil {
+		schema, err := ps.load()
+		if err != nil {
+			panic(err)
+		}
+		if schema == nil {
+			pendingSchema = ps
+		}
+	}
+	registered := false
+	defaultOpts = append(defaultOpts, withCreateHook(func(ctx *ContainerContext) {
+		te.registerAppContainer(ctx)
+		registered = true
+	}))
 	appCtx, err := te.cli.RunAppContainer(
 		image,
 		localConfig,
 		remoteConfig,
 		defaultOpts...,
 	)
-	te.appContainers = append(te.appContainers, appCtx)
-	te.makeBackupFile()
+	if !registered {
+		te.registerAppContainer(appCtx)
+	}
 	if err != nil {
 		panic(err)
 	}
+	// schema is not published before the first start of module, so config is validated right after start
+	if pendingSchema != nil {
+		if err := pendingSchema.validateAfterStart(remoteConfig, publishedSchemaTimeout); err != nil {
+			te.reportError(err)
+		}
+	}
 	return appCtx
 }
 
@@ -91,6 +181,8 @@ func (te *TestEnvironment) RunConfigServiceContainer(opts ...Option) (*Container
 	return cfgCtx, configServiceAddr
 }
 
+// run postgres container, if init scripts or extensions are set
+// waits until scripts are executed and server is restarted, then creates extensions
 func (te *TestEnvironment) RunPGContainer(opts ...Option) (*ContainerContext, structure.DBConfiguration) {
 	pgCfg := te.testCtx.GetDBConfiguration()
 	defaultOpts := []Option{
@@ -100,33 +192,108 @@ func (te *TestEnvironment) RunPGContainer(opts ...Option) (*ContainerContext, st
 	}
 	defaultOpts = append(defaultOpts, opts...)
 	pgCtx, err := te.cli.RunPGContainer(
-		DefaultPGImage,
+		te.testCtx.Infra().Postgres.Image,
 		pgCfg.Database,
 		pgCfg.Password,
 		defaultOpts...,
 	)
-	te.basicContainers = append(te.basicContainers, pgCtx)
+	te.addBasicContainer(pgCtx)
 	te.makeBackupFile()
 	if err != nil {
 		panic(err)
 	}
+	if pgOpts := newOptions(defaultOpts).pg; pgOpts.hasInit() {
+		timeout := pgOpts.initTimeout
+		if timeout <= 0 {
+			timeout = DefaultPGInitTimeout
+		}
+		if err := pgCtx.waitPGInit(timeout); err != nil {
+			panic(err)
+		}
+		if err := pgCtx.createPGExtensions(pgCfg.Database, pgOpts.extensions); err != nil {
+			panic(err)
+		}
+	}
 	pgCfg.Address = pgCtx.GetIPAddress()
 	return pgCtx, pgCfg
 }
 
+func (te *TestEnvironment) RunMongoContainer(opts ...Option) (*ContainerContext, structure.DBConfiguration) {
+	mongoCfg := te.testCtx.GetMongoConfiguration()
+	defaultOpts := []Option{
+		WithName(mongoCfg.Address),
+		WithNetwork(te.network),
+		PullImage("", ""),
+		WithEnv(map[string]string{
+			"MONGO_INITDB_ROOT_USERNAME": mongoCfg.Username,
+			"MONGO_INITDB_ROOT_PASSWORD": mongoCfg.Password,
+			"MONGO_INITDB_DATABASE":      mongoCfg.Database,
+		}),
+	}
+	defaultOpts = append(defaultOpts, opts...)
+	mongoCtx, err := te.cli.RunContainer(
+		te.testCtx.Infra().Mongo.Image,
+		defaultOpts...,
+	)
+	te.addBasicContainer(mongoCtx)
+	te.makeBackupFile()
+	if err != nil {
+		panic(err)
+	}
+	mongoCfg.Address = mongoCtx.GetIPAddress()
+	return mongoCtx, mongoCfg
+}
+
+// clickhouse entrypoint creates CLICKHOUSE_DB without quoting, so database name must be plain identifier
+var clickHouseIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (te *TestEnvironment) RunClickHouseContainer(opts ...Option) (*ContainerContext, structure.DBConfiguration) {
+	chCfg := te.testCtx.GetClickHouseConfiguration()
+	if !clickHouseIdentifierRegexp.MatchString(chCfg.Database) {
+		panic(fmt.Errorf("invalid clickhouse database name %q: letters, digits and underscores are allowed", chCfg.Database))
+	}
+	defaultOpts := []Option{
+		WithName(chCfg.Address),
+		WithNetwork(te.network),
+		PullImage("", ""),
+		WithEnv(map[string]string{
+			"CLICKHOUSE_DB":                        chCfg.Database,
+			"CLICKHOUSE_USER":                      chCfg.Username,
+			"CLICKHOUSE_PASSWORD":                  chCfg.Password,
+			"CLICKHOUSE_DEFAULT_ACCESS_MANAGEMENT": "1",
+		}),
+	}
+	defaultOpts = append(defaultOpts, opts...)
+	chCtx, err := te.cli.RunContainer(
+		te.testCtx.Infra().ClickHouse.Image,
+		defaultOpts...,
+	)
+	te.addBasicContainer(chCtx)
+	te.makeBackupFile()
+	if err != nil {
+		panic(err)
+	}
+	chCfg.Address = chCtx.GetIPAddress()
+	return chCtx, chCfg
+}
+
 func (te *TestEnvironment) RunRabbitContainer(opts ...Option) (*ContainerContext, mq.Config) {
 	rabbitCfg := te.testCtx.GetRabbitConfiguration()
 	defaultOpts := []Option{
 		WithName(rabbitCfg.Address.IP),
 		WithNetwork(te.network),
 		PullImage("", ""),
+		WithEnv(map[string]string{
+			"RABBITMQ_DEFAULT_USER": rabbitCfg.User,
+			"RABBITMQ_DEFAULT_PASS": rabbitCfg.Password,
+		}),
 	}
 	defaultOpts = append(defaultOpts, opts...)
 	rabbitCtx, err := te.cli.RunContainer(
-		DefaultRabbitImage,
+		te.testCtx.Infra().Rabbit.Image,
 		defaultOpts...,
 	)
-	te.basicContainers = append(te.basicContainers, rabbitCtx)
+	te.addBasicContainer(rabbitCtx)
 	te.makeBackupFile()
 	if err != nil {
 		panic(err)
@@ -146,10 +313,10 @@ func (te *TestEnvironment) RunElasticContainer(opts ...Option) (*ContainerContex
 	}
 	defaultOpts = append(defaultOpts, opts...)
 	elasticCtx, err := te.cli.RunContainer(
-		DefaultElasticImage,
+		te.testCtx.Infra().Elastic.Image,
 		defaultOpts...,
 	)
-	te.basicContainers = append(te.basicContainers, elasticCtx)
+	te.addBasicContainer(elasticCtx)
 	te.makeBackupFile()
 	if err != nil {
 		panic(err)
@@ -158,6 +325,157 @@ func (te *TestEnvironment) RunElasticContainer(opts ...Option) (*ContainerContex
 	return elasticCtx, elasticConfig
 }
 
+// RunJobContainer runs a one-shot container (migrations, seeders, cli jobs) and waits until it exits
+// returns combined stdout and stderr output and the exit code
+func (te *TestEnvironment) RunJobContainer(image string, timeout time.Duration, opts ...Option) ([]byte, int) {
+	defaultOpts := []Option{
+		WithNetwork(te.network),
+	}
+	defaultOpts = append(defaultOpts, opts...)
+	jobCtx, err := te.cli.RunContainer(
+		image,
+		defaultOpts...,
+	)
+	jobCtx.expectExit(true)
+	te.addBasicContainer(jobCtx)
+	te.makeBackupFile()
+	if err != nil {
+		panic(err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	exitCode, err := jobCtx.Wait(waitCtx, WaitNotRunning)
+	if err != nil {
+		panic(err)
+	}
+	output, err := jobCtx.output()
+	if err != nil {
+		panic(err)
+	}
+	return output, exitCode
+}
+
+// returns false if cleanup was already started
+func (te *TestEnvironment) markCleanup() bool {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	if te.cleanupFlag {
+		return false
+	}
+	te.cleanupFlag = true
+	return true
+}
+
+// returns all environment containers, basic containers first
+func (te *TestEnvironment) containers() []*ContainerContext {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	result := make([]*ContainerContext, 0, len(te.basicContainers)+len(te.appContainers))
+	result = append(result, te.basicContainers...)
+	return append(result, te.appContainers...)
+}
+
+// returns snapshot of app containers, slice is changed concurrently by replica scaling
+func (te *TestEnvironment) applications() []*ContainerContext {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	return append([]*ContainerContext(nil), te.appContainers...)
+}
+
+// app container is registered before start, so crash watcher does not miss exit during startup
+func (te *TestEnvironment) registerAppContainer(container *ContainerContext) {
+	te.addAppContainer(container)
+	te.makeBackupFile()
+}
+
+func (te *TestEnvironment) addAppContainer(container *ContainerContext) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.appContainers = append(te.appContainers, container)
+}
+
+func (te *TestEnvironment) removeAppContainer(container *ContainerContext) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	for i := range te.appContainers {
+		if te.appContainers[i] == container {
+			te.appContainers = append(te.appContainers[:i], te.appContainers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (te *TestEnvironment) addBasicContainer(container *ContainerContext) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.basicContainers = append(te.basicContainers, container)
+}
+
+func (te *TestEnvironment) addCloser(closer io.Closer) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.closers = append(te.closers, closer)
+}
+
+func (te *TestEnvironment) RunRedisContainer(opts ...Option) (*ContainerContext, structure.RedisConfiguration) {
+	redisCfg := te.testCtx.GetRedisConfiguration()
+	defaultOpts := []Option{
+		WithName(redisCfg.Address.IP),
+		WithNetwork(te.network),
+		PullImage("", ""),
+	}
+	if redisCfg.Password != "" {
+		defaultOpts = append(defaultOpts, WithCmd("redis-server", "--requirepass", redisCfg.Password))
+	}
+	defaultOpts = append(defaultOpts, opts...)
+	redisCtx, err := te.cli.RunContainer(
+		te.testCtx.Infra().Redis.Image,
+		defaultOpts...,
+	)
+	te.addBasicContainer(redisCtx)
+	te.makeBackupFile()
+	if err != nil {
+		panic(err)
+	}
+	redisCfg.Address.IP = redisCtx.GetIPAddress()
+	return redisCtx, redisCfg
+}
+
+// run MinIO container with credentials generated for the session
+func (te *TestEnvironment) RunObjectStorageContainer(opts ...Option) (*ContainerContext, ctx.ObjectStorageConfiguration) {
+	s3Cfg := te.testCtx.GetObjectStorageConfiguration()
+	containerName, _, err := net.SplitHostPort(s3Cfg.Endpoint)
+	if err != nil {
+		panic(err)
+	}
+	s3Cfg.AccessKey = "isp-test-" + randomHex(4)
+	s3Cfg.SecretKey = randomHex(16)
+	defaultOpts := []Option{
+		WithName(containerName),
+		WithNetwork(te.network),
+		PullImage("", ""),
+		WithEnv(map[string]string{
+			"MINIO_ROOT_USER":     s3Cfg.AccessKey,
+			"MINIO_ROOT_PASSWORD": s3Cfg.SecretKey,
+			"MINIO_REGION_NAME":   s3Cfg.Region,
+		}),
+		WithCmd("server", "/data"),
+	}
+	defaultOpts = append(defaultOpts, opts...)
+	s3Ctx, err := te.cli.RunContainer(
+		te.testCtx.Infra().ObjectStorage.Image,
+		defaultOpts...,
+	)
+	te.addBasicContainer(s3Ctx)
+	te.makeBackupFile()
+	if err != nil {
+		panic(err)
+	}
+	s3Cfg.Endpoint = net.JoinHostPort(s3Ctx.GetIPAddress(), ctx.ObjectStoragePort)
+	return s3Ctx, s3Cfg
+}
+
 func (te *TestEnvironment) signalCleanupper() {
 	quit := make(chan os.Signal, 2)
 	signal.Notify(quit, signals...)
@@ -204,6 +522,9 @@ func NewTestEnvironment(testCtx *ctx.TestContext, cli *ispDockerClient) *TestEnv
 		},
 	}
 	env.makeBackupFile()
+	eventsCtx, cancel := context.WithCancel(context.Background())
+	env.cancelEvents = cancel
+	go env.watchEvents(eventsCtx)
 	go env.signalCleanupper()
 	return env
 }
@@ -0,0 +1,21 @@
+package docker
+
+import (
+	"github.com/integration-system/isp-lib-test/tracing"
+)
+
+// start in-process OTLP trace collector bound to the bridge address, so containers can export spans to it
+// pass Collector.ExporterEnv to app container through WithEnv or AddEnv
+// collector is closed on Cleanup
+func (te *TestEnvironment) RunTraceCollector() *tracing.Collector {
+	host, err := te.cli.GetBridgeAddress()
+	if err != nil {
+		panic(err)
+	}
+	collector, err := tracing.NewCollector(host)
+	if err != nil {
+		panic(err)
+	}
+	te.addCloser(collector)
+	return collector
+}
@@ -1,6 +1,8 @@
 package docker
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -78,3 +80,12 @@ func toString(value interface{}) (string, config.PropertyType) {
 		return "", ""
 	}
 }
+
+// returns random hex string of n bytes
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -3,19 +3,29 @@ module github.com/integration-system/isp-lib-test
 go 1.16
 
 require (
+	github.com/ClickHouse/clickhouse-go v1.4.5
 	github.com/docker/docker v20.10.5+incompatible
 	github.com/docker/go-connections v0.4.0
 	github.com/go-pg/pg/v9 v9.2.1
+	github.com/go-redis/redis/v8 v8.8.2
 	github.com/hashicorp/go-multierror v1.1.1
 	github.com/integration-system/bellows v1.0.1
+	github.com/integration-system/isp-etp-go/v2 v2.1.3
 	github.com/integration-system/isp-event-lib v1.6.5
 	github.com/integration-system/isp-lib/v2 v2.10.0
 	github.com/integration-system/isp-log v1.1.8
+	github.com/minio/minio-go/v7 v7.0.12
 	github.com/olivere/elastic v6.2.29+incompatible
 	github.com/pkg/errors v0.9.1
+	github.com/segmentio/kafka-go v0.4.17
 	github.com/spf13/viper v1.8.1
 	github.com/streadway/amqp v1.0.0
 	github.com/stretchr/testify v1.7.0
+	github.com/xeipuuv/gojsonschema v1.2.0
+	go.mongodb.org/mongo-driver v1.7.3
+	go.opentelemetry.io/proto/otlp v0.9.0
+	google.golang.org/genproto v0.0.0-20210602131652-f16073e35f0c
 	google.golang.org/grpc v1.38.0
+	google.golang.org/protobuf v1.26.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,65 @@
+package httpmock
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Calls returns requests matched by stub
+func (s *Stub) Calls() []Request {
+	result := make([]Request, 0)
+	for _, r := range s.server.Requests() {
+		if r.Stub == s {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// AssertCalled fails t if stub was not matched exactly n times
+func (s *Stub) AssertCalled(t assert.TestingT, n int) bool {
+	calls := s.Calls()
+	return assert.Len(t, calls, n, "unexpected number of calls of stub %s /%s", s.method, strings.Join(s.segments, "/"))
+}
+
+// AssertCalled fails t if requests with method and path were not received exactly n times
+// path can contain {name} segments, empty method matches any method
+func (s *Server) AssertCalled(t assert.TestingT, method, path string, n int) bool {
+	pattern := splitPath(path)
+	method = strings.ToUpper(method)
+	count := 0
+	for _, r := range s.Requests() {
+		if method != "" && method != r.Method {
+			continue
+		}
+		if _, ok := matchPath(pattern, splitPath(r.Path)); ok {
+			count++
+		}
+	}
+	return assert.Equal(t, n, count, "unexpected number of %s %s requests, received requests:\n%s",
+		method, path, formatRequests(s.Requests()))
+}
+
+// AssertNotCalled fails t if any request with method and path was received
+func (s *Server) AssertNotCalled(t assert.TestingT, method, path string) bool {
+	return s.AssertCalled(t, method, path, 0)
+}
+
+// AssertAllMatched fails t if some received request matched no stub
+func (s *Server) AssertAllMatched(t assert.TestingT) bool {
+	unmatched := s.UnmatchedRequests()
+	if len(unmatched) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("%d requests matched no stub", len(unmatched)), formatRequests(unmatched))
+}
+
+func formatRequests(requests []Request) string {
+	lines := make([]string, 0, len(requests))
+	for _, r := range requests {
+		lines = append(lines, r.String())
+	}
+	return strings.Join(lines, "\n")
+}
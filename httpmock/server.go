@@ -0,0 +1,192 @@
+package httpmock
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/integration-system/isp-lib/v2/structure"
+	"github.com/pkg/errors"
+)
+
+// Request is a journaled request received by Server
+type Request struct {
+	Time    time.Time
+	Method  string
+	Path    string
+	Query   map[string][]string
+	Headers http.Header
+	Body    []byte
+	// path parameters declared as {name} in stub path
+	Vars map[string]string
+	// nil if request matched no stub
+	Stub *Stub
+}
+
+func (r Request) String() string {
+	return fmt.Sprintf("%s %s %s", r.Method, r.Path, string(r.Body))
+}
+
+// Server is HTTP server started in the test process to stub third-party APIs called by containers
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+
+	mu      sync.Mutex
+	stubs   []*Stub
+	journal []Request
+}
+
+// NewServer starts server on random port of host
+// use bridge address as host to make server reachable from containers
+func NewServer(host string) (*Server, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, errors.Wrap(err, "listen http mock")
+	}
+	s := &Server{listener: listener}
+	s.server = &http.Server{Handler: s}
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// returns base url of server, e.g. http://172.17.0.1:34567
+func (s *Server) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+func (s *Server) Address() structure.AddressConfiguration {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return structure.AddressConfiguration{IP: host, Port: port}
+}
+
+// RemoteConfig returns remote configuration with server url set at dot-separated key,
+// e.g. RemoteConfig("partner.url") returns {"partner": {"url": "http://..."}}
+// result can be passed as remoteConfig to RunAppContainer or merged into it with MergeConfig
+func (s *Server) RemoteConfig(key string) map[string]interface{} {
+	parts := strings.Split(key, ".")
+	var value interface{} = s.URL()
+	for i := len(parts) - 1; i >= 0; i-- {
+		value = map[string]interface{}{parts[i]: value}
+	}
+	return value.(map[string]interface{})
+}
+
+// MergeConfig recursively merges src into dst map and returns dst
+func MergeConfig(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		srcMap, srcOk := v.(map[string]interface{})
+		dstMap, dstOk := dst[k].(map[string]interface{})
+		if srcOk && dstOk {
+			dst[k] = MergeConfig(dstMap, srcMap)
+		} else {
+			dst[k] = v
+		}
+	}
+	return dst
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// On creates stub for method and path, later registered stubs take precedence
+// path segments like {id} match any value and are available in response templates as .Vars.id
+// empty method matches any method
+// stub starts matching requests after the first Reply* or Register call, stubs can be added while server is in use
+func (s *Server) On(method, path string) *Stub {
+	return newStub(s, strings.ToUpper(method), path)
+}
+
+// Reset removes all stubs and journaled requests
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stubs = nil
+	s.journal = nil
+}
+
+// Requests returns all received requests
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.journal...)
+}
+
+// UnmatchedRequests returns received requests which matched no stub
+func (s *Server) UnmatchedRequests() []Request {
+	result := make([]Request, 0)
+	for _, r := range s.Requests() {
+		if r.Stub == nil {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := Request{
+		Time:    time.Now(),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.Query(),
+		Headers: r.Header.Clone(),
+		Body:    body,
+	}
+
+	s.mu.Lock()
+	var resp *Response
+	for i := len(s.stubs) - 1; i >= 0; i-- {
+		stub := s.stubs[i]
+		if vars, ok := stub.match(req); ok {
+			req.Vars = vars
+			req.Stub = stub
+			resp = stub.nextResponse()
+			break
+		}
+	}
+	s.journal = append(s.journal, req)
+	s.mu.Unlock()
+
+	if resp == nil {
+		http.Error(w, fmt.Sprintf("httpmock: no stub for %s %s", req.Method, req.Path), http.StatusNotFound)
+		return
+	}
+	if resp.delay > 0 {
+		select {
+		case <-time.After(resp.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	respBody, err := resp.render(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for k, values := range resp.headers {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(respBody)))
+	w.WriteHeader(resp.status)
+	_, _ = bytes.NewReader(respBody).WriteTo(w)
+}
@@ -0,0 +1,88 @@
+package httpmock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_AddStubsWhileServing(t *testing.T) {
+	server, err := NewServer("127.0.0.1")
+	require.NoError(t, err)
+	defer server.Close()
+	server.On("POST", "/items/{id}").Reply(http.StatusOK, "default")
+
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				url := fmt.Sprintf("%s/items/%d", server.URL(), j%10)
+				req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"kind":"other"}`))
+				req.Header.Set("X-Worker", fmt.Sprint(i))
+				resp, err := http.DefaultClient.Do(req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				body, _ := ioutil.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				// stubs requiring other body must never match, even while being configured
+				assert.Equal(t, "default", string(body))
+			}
+		}(i)
+	}
+
+	// keep adding stubs until enough requests are served concurrently
+	deadline := time.Now().Add(30 * time.Second)
+	for i := 0; len(server.Requests()) < 300; i++ {
+		require.True(t, time.Now().Before(deadline), "only %d requests served", len(server.Requests()))
+		server.On("POST", fmt.Sprintf("/items/%d", i%10)).
+			MatchHeader("X-Worker", "0").
+			MatchQuery("q", "1").
+			MatchJSON(map[string]interface{}{"kind": "special"}).
+			Reply(http.StatusCreated, "special").
+			Header("X-Stub", fmt.Sprint(i)).
+			Delay(0).
+			ReplyJSON(http.StatusOK, map[string]int{"n": i})
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Empty(t, server.UnmatchedRequests())
+}
+
+func TestServer_StubWithoutResponseIsNotMatched(t *testing.T) {
+	server, err := NewServer("127.0.0.1")
+	require.NoError(t, err)
+	defer server.Close()
+
+	stub := server.On("GET", "/ping")
+	resp, err := http.Get(server.URL() + "/ping")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	stub.Register()
+	resp, err = http.Get(server.URL() + "/ping")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	stub.AssertCalled(t, 1)
+}
@@ -0,0 +1,303 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Stub describes requests to match and responses to return
+// responses are returned in order they are added, the last one is repeated
+// stub is registered in server by the first Reply* or Register call, so declare matchers first
+type Stub struct {
+	server     *Server
+	method     string
+	segments   []string
+	headers    map[string]string
+	query      map[string]string
+	body       []func([]byte) bool
+	responses  []*Response
+	served     int
+	registered bool
+}
+
+type Response struct {
+	status   int
+	headers  http.Header
+	body     []byte
+	template *template.Template
+	delay    time.Duration
+}
+
+// data available in response templates
+type templateData struct {
+	Method  string
+	Path    string
+	Query   map[string][]string
+	Headers http.Header
+	Vars    map[string]string
+	Body    string
+	// request body decoded as json, nil if body is not valid json
+	JSON interface{}
+}
+
+func newStub(server *Server, method, path string) *Stub {
+	return &Stub{
+		server:   server,
+		method:   method,
+		segments: splitPath(path),
+		headers:  make(map[string]string),
+		query:    make(map[string]string),
+	}
+}
+
+// MatchHeader requires request header to be equal to value
+func (s *Stub) MatchHeader(key, value string) *Stub {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.headers[http.CanonicalHeaderKey(key)] = value
+	return s
+}
+
+// MatchQuery requires query parameter to be equal to value
+func (s *Stub) MatchQuery(key, value string) *Stub {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.query[key] = value
+	return s
+}
+
+// MatchJSON requires request body to be json containing all fields of expected
+// expected can be map, struct or json string, nested objects are matched as subsets too
+func (s *Stub) MatchJSON(expected interface{}) *Stub {
+	exp, err := toJSONValue(expected)
+	if err != nil {
+		panic(errors.Wrap(err, "httpmock: invalid expected json"))
+	}
+	return s.MatchBody(func(body []byte) bool {
+		var actual interface{}
+		if err := json.Unmarshal(body, &actual); err != nil {
+			return false
+		}
+		return jsonContains(actual, exp)
+	})
+}
+
+// MatchBodyContains requires request body to contain substr
+func (s *Stub) MatchBodyContains(substr string) *Stub {
+	return s.MatchBody(func(body []byte) bool {
+		return bytes.Contains(body, []byte(substr))
+	})
+}
+
+// MatchBody requires f to return true for request body
+func (s *Stub) MatchBody(f func(body []byte) bool) *Stub {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.body = append(s.body, f)
+	return s
+}
+
+// Register registers stub replying 200 with empty body until responses are added
+func (s *Stub) Register() *Stub {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.register()
+	return s
+}
+
+// Reply adds response with status and raw body and registers stub
+func (s *Stub) Reply(status int, body string) *Stub {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.addResponse(&Response{status: status, headers: make(http.Header), body: []byte(body)})
+	return s
+}
+
+// ReplyJSON adds response with status and v encoded as json and registers stub
+func (s *Stub) ReplyJSON(status int, v interface{}) *Stub {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(errors.Wrap(err, "httpmock: marshal response"))
+	}
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.addResponse(&Response{status: status, headers: headers, body: body})
+	return s
+}
+
+// ReplyTemplate adds response with status and body rendered by text/template for each request and registers stub
+// template data has fields Method, Path, Query, Headers, Vars, Body and JSON (decoded request body)
+func (s *Stub) ReplyTemplate(status int, tmpl string) *Stub {
+	t, err := template.New("response").Parse(tmpl)
+	if err != nil {
+		panic(errors.Wrap(err, "httpmock: parse response template"))
+	}
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.addResponse(&Response{status: status, headers: make(http.Header), template: t})
+	return s
+}
+
+// Header sets header of the last added response
+func (s *Stub) Header(key, value string) *Stub {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.last().headers.Set(key, value)
+	return s
+}
+
+// Delay delays the last added response
+func (s *Stub) Delay(d time.Duration) *Stub {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.last().delay = d
+	return s
+}
+
+// must be called with server lock held
+func (s *Stub) addResponse(resp *Response) {
+	s.responses = append(s.responses, resp)
+	s.register()
+}
+
+// must be called with server lock held
+func (s *Stub) register() {
+	if !s.registered {
+		s.registered = true
+		s.server.stubs = append(s.server.stubs, s)
+	}
+}
+
+// must be called with server lock held
+func (s *Stub) last() *Response {
+	if len(s.responses) == 0 {
+		s.addResponse(&Response{status: http.StatusOK, headers: make(http.Header)})
+	}
+	return s.responses[len(s.responses)-1]
+}
+
+// returns copy of the next response, so it can be used after server lock is released
+func (s *Stub) nextResponse() *Response {
+	if len(s.responses) == 0 {
+		return &Response{status: http.StatusOK}
+	}
+	i := s.served
+	if i >= len(s.responses) {
+		i = len(s.responses) - 1
+	}
+	s.served++
+	resp := *s.responses[i]
+	resp.headers = resp.headers.Clone()
+	return &resp
+}
+
+func (s *Stub) match(req Request) (map[string]string, bool) {
+	if s.method != "" && s.method != req.Method {
+		return nil, false
+	}
+	vars, ok := matchPath(s.segments, splitPath(req.Path))
+	if !ok {
+		return nil, false
+	}
+	for k, v := range s.headers {
+		if req.Headers.Get(k) != v {
+			return nil, false
+		}
+	}
+	for k, v := range s.query {
+		values, ok := req.Query[k]
+		if !ok || len(values) == 0 || values[0] != v {
+			return nil, false
+		}
+	}
+	for _, f := range s.body {
+		if !f(req.Body) {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+func (r *Response) render(req Request) ([]byte, error) {
+	if r.template == nil {
+		return r.body, nil
+	}
+	data := templateData{
+		Method:  req.Method,
+		Path:    req.Path,
+		Query:   req.Query,
+		Headers: req.Headers,
+		Vars:    req.Vars,
+		Body:    string(req.Body),
+	}
+	_ = json.Unmarshal(req.Body, &data.JSON)
+	buf := new(bytes.Buffer)
+	if err := r.template.Execute(buf, data); err != nil {
+		return nil, errors.Wrap(err, "httpmock: execute response template")
+	}
+	return buf.Bytes(), nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func matchPath(pattern, path []string) (map[string]string, bool) {
+	if len(pattern) != len(path) {
+		return nil, false
+	}
+	vars := make(map[string]string)
+	for i, segment := range pattern {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			vars[segment[1:len(segment)-1]] = path[i]
+		} else if segment != path[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	var data []byte
+	switch value := v.(type) {
+	case string:
+		data = []byte(value)
+	case []byte:
+		data = value
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	var result interface{}
+	err := json.Unmarshal(data, &result)
+	return result, err
+}
+
+func jsonContains(actual, expected interface{}) bool {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range exp {
+			if !jsonContains(act[k], v) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(actual, expected)
+	}
+}
@@ -0,0 +1,50 @@
+package mockmodule
+
+import (
+	"fmt"
+	"strings"
+
+	ispUtils "github.com/integration-system/isp-lib/v2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// AssertCalled fails t if endpoint was not called exactly n times
+func (m *Module) AssertCalled(t assert.TestingT, path string, n int) bool {
+	return assert.Len(t, m.Calls(path), n, "unexpected number of calls of %s/%s, recorded calls:\n%s",
+		m.name, path, formatCalls(m.Calls("")))
+}
+
+// AssertNotCalled fails t if endpoint was called
+func (m *Module) AssertNotCalled(t assert.TestingT, path string) bool {
+	return m.AssertCalled(t, path, 0)
+}
+
+// AssertCalledWith fails t if the last call of endpoint has body not equal to expected json
+// expected can be map, struct or json string, structs are encoded the same way as isp modules do
+func (m *Module) AssertCalledWith(t assert.TestingT, path string, expected interface{}) bool {
+	call, ok := m.LastCall(path)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("%s/%s was not called", m.name, path))
+	}
+	expectedJson, ok := expected.(string)
+	if !ok {
+		data, err := ispUtils.ConvertGoToBytes(expected)
+		if !assert.NoError(t, err) {
+			return false
+		}
+		expectedJson = string(data)
+	}
+	return assert.JSONEq(t, expectedJson, string(call.Request))
+}
+
+func formatCalls(calls []Call) string {
+	lines := make([]string, 0, len(calls))
+	for _, c := range calls {
+		line := fmt.Sprintf("%s %s", c.Method, string(c.Request))
+		if c.Err != nil {
+			line += fmt.Sprintf(" error: %v", c.Err)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,244 @@
+package mockmodule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	etp "github.com/integration-system/isp-etp-go/v2/client"
+	"github.com/integration-system/isp-lib-test/ctx"
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/integration-system/isp-lib/v2/backend"
+	"github.com/integration-system/isp-lib/v2/bootstrap"
+	"github.com/integration-system/isp-lib/v2/config/schema"
+	"github.com/integration-system/isp-lib/v2/isp"
+	"github.com/integration-system/isp-lib/v2/structure"
+	ispUtils "github.com/integration-system/isp-lib/v2/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	DefaultVersion = "1.0.0-mock"
+
+	ackTimeout = 3 * time.Second
+)
+
+// Call is a recorded invocation of mock module endpoint
+type Call struct {
+	Time     time.Time
+	Method   string
+	Metadata metadata.MD
+	Request  []byte
+	Response []byte
+	Err      error
+}
+
+// Decode unmarshals request body of call into ptr the same way as isp modules do
+func (c Call) Decode(ptr interface{}) error {
+	return ispUtils.ConvertBytesToGo(c.Request, ptr)
+}
+
+// Module pretends to be isp module: it connects to config-service, declares endpoints
+// and serves them over gRPC with Go handlers, every call is recorded
+type Module struct {
+	name      string
+	version   string
+	endpoints []structure.EndpointDescriptor
+
+	listener net.Listener
+	server   *grpc.Server
+	client   etp.Client
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func New(moduleName string) *Module {
+	return &Module{
+		name:    moduleName,
+		version: DefaultVersion,
+	}
+}
+
+func (m *Module) Name() string {
+	return m.name
+}
+
+// WithVersion sets version declared to config-service
+func (m *Module) WithVersion(version string) *Module {
+	m.version = version
+	return m
+}
+
+// Handle declares endpoint served by handler
+// handler has the same signature as in isp modules: func([ctx] [metadata] [request]) (response, error)
+func (m *Module) Handle(path string, handler interface{}) *Module {
+	m.endpoints = append(m.endpoints, structure.EndpointDescriptor{Path: path, Handler: handler})
+	return m
+}
+
+// Respond declares endpoint always returning response
+func (m *Module) Respond(path string, response interface{}) *Module {
+	return m.Handle(path, func() (interface{}, error) {
+		return response, nil
+	})
+}
+
+// RespondError declares endpoint always returning err, use status.Error to return specific grpc code
+func (m *Module) RespondError(path string, err error) *Module {
+	return m.Handle(path, func() (interface{}, error) {
+		return nil, err
+	})
+}
+
+// Start serves declared endpoints on random port of host and registers module in config-service
+// use bridge address as host to make module reachable from containers
+// configAddr is the config-service address returned by RunConfigServiceContainer or RunConfigServiceCluster
+func (m *Module) Start(host string, configAddr structure.AddressConfiguration, timeout time.Duration) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return errors.Wrap(err, "listen mock module")
+	}
+	m.listener = listener
+
+	service := backend.NewDefaultService(m.endpoints).WithPostProcessors(m.record)
+	m.server = grpc.NewServer()
+	isp.RegisterBackendServiceServer(m.server, service)
+	go func() {
+		_ = m.server.Serve(listener)
+	}()
+
+	if err := m.connect(configAddr, timeout); err != nil {
+		m.server.Stop()
+		return err
+	}
+	return nil
+}
+
+// Address returns address of mock module gRPC server
+func (m *Module) Address() structure.AddressConfiguration {
+	host, port, _ := net.SplitHostPort(m.listener.Addr().String())
+	return structure.AddressConfiguration{IP: host, Port: port}
+}
+
+// Close disconnects module from config-service and stops gRPC server
+func (m *Module) Close() error {
+	var err error
+	if m.client != nil && !m.client.Closed() {
+		err = m.client.Close()
+	}
+	if m.server != nil {
+		m.server.Stop()
+	}
+	return err
+}
+
+// Calls returns recorded calls of endpoint, all calls if path is empty
+func (m *Module) Calls(path string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]Call, 0)
+	for _, c := range m.calls {
+		if path == "" || c.Method == path {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// LastCall returns the last recorded call of endpoint
+func (m *Module) LastCall(path string) (Call, bool) {
+	calls := m.Calls(path)
+	if len(calls) == 0 {
+		return Call{}, false
+	}
+	return calls[len(calls)-1], true
+}
+
+// Reset removes recorded calls
+func (m *Module) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+}
+
+func (m *Module) record(ctx backend.RequestCtx) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call{
+		Time:     time.Now(),
+		Method:   ctx.Method(),
+		Metadata: ctx.Metadata(),
+		Request:  ctx.RequestBody(),
+		Response: ctx.ResponseBody(),
+		Err:      ctx.Error(),
+	})
+}
+
+func (m *Module) connect(configAddr structure.AddressConfiguration, timeout time.Duration) error {
+	params := url.Values{}
+	params.Set(ispUtils.ModuleNameGetParamKey, m.name)
+	addrs := ctx.SplitAddresses(configAddr)
+
+	attempt := 0
+	_, err := utils.AwaitConnection(func() (interface{}, error) {
+		// try cluster nodes in turn
+		addr := addrs[attempt%len(addrs)]
+		attempt++
+		connectionString := fmt.Sprintf("ws://%s/isp-etp/?%s", addr.GetAddress(), params.Encode())
+		client := etp.NewClient(etp.Config{HttpClient: &http.Client{}})
+		// dial context lives as long as connection
+		if err := client.Dial(context.Background(), connectionString); err != nil {
+			return nil, err
+		}
+		m.client = client
+		return nil, nil
+	}, timeout)
+	if err != nil {
+		return errors.Wrap(err, "connect to config service")
+	}
+
+	configSchema := schema.ConfigSchema{
+		Version: m.version,
+		Schema:  schema.GenerateConfigSchema(&struct{}{}),
+	}
+	if err := m.emit(ispUtils.ModuleSendConfigSchema, configSchema); err != nil {
+		return err
+	}
+	if err := m.emit(ispUtils.ModuleSendRequirements, bootstrap.ModuleRequirements{RequiredModules: []string{}}); err != nil {
+		return err
+	}
+	declaration := structure.BackendDeclaration{
+		ModuleName:      m.name,
+		Version:         m.version,
+		LibVersion:      bootstrap.LibraryVersion,
+		Endpoints:       m.endpoints,
+		RequiredModules: []structure.ModuleDependency{},
+		Address:         m.Address(),
+	}
+	return m.emit(ispUtils.ModuleReady, declaration)
+}
+
+func (m *Module) emit(event string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrapf(err, "marshal %s", event)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+	resp, err := m.client.EmitWithAck(ctx, event, body)
+	if err != nil {
+		return errors.Wrapf(err, "emit %s", event)
+	}
+	if string(resp) != ispUtils.WsOkResponse {
+		return errors.Errorf("emit %s: unexpected response %s", event, resp)
+	}
+	return nil
+}
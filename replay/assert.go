@@ -0,0 +1,42 @@
+package replay
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// journal of requests which have no recorded interaction in replay mode
+type unmatchedJournal struct {
+	mu        sync.Mutex
+	unmatched []Interaction
+}
+
+func (j *unmatchedJournal) addUnmatched(request Interaction) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.unmatched = append(j.unmatched, request)
+}
+
+// Unmatched returns requests received in replay mode which have no recorded interaction
+func (j *unmatchedJournal) Unmatched() []Interaction {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Interaction(nil), j.unmatched...)
+}
+
+// AssertAllMatched checks that every request was served from cassette,
+// unmatched requests mean that module behaviour changed and cassette must be recorded again
+func (j *unmatchedJournal) AssertAllMatched(t assert.TestingT) bool {
+	unmatched := j.Unmatched()
+	if len(unmatched) == 0 {
+		return true
+	}
+	lines := make([]string, len(unmatched))
+	for i, request := range unmatched {
+		lines[i] = "  " + request.String()
+	}
+	return assert.Fail(t, "requests have no recorded interactions, record cassette again",
+		strings.Join(lines, "\n"))
+}
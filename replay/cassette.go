@@ -0,0 +1,176 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type Mode int
+
+const (
+	// proxy forwards requests to dependency and records exchanges
+	ModeRecord Mode = iota
+	// proxy serves recorded responses, dependency is not called
+	ModeReplay
+)
+
+func (m Mode) String() string {
+	if m == ModeReplay {
+		return "replay"
+	}
+	return "record"
+}
+
+const (
+	KindGrpc = "grpc"
+	KindHttp = "http"
+)
+
+// Interaction is a recorded request to dependency and its response
+type Interaction struct {
+	Kind string `json:"kind"`
+	// isp endpoint path for grpc, request method for http
+	Method string `json:"method"`
+	// request path with query for http
+	Path     string `json:"path,omitempty"`
+	Request  string `json:"request,omitempty"`
+	Response string `json:"response,omitempty"`
+	// grpc status code and message if call failed
+	Code    uint32 `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	// http response status and headers
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (i Interaction) String() string {
+	if i.Kind == KindHttp {
+		return strings.TrimSpace(fmt.Sprintf("%s %s %s", i.Method, i.Path, i.Request))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s", i.Method, i.Request))
+}
+
+// Cassette is a file with interactions recorded in the same order they happened
+type Cassette struct {
+	path string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	repeats      bool
+}
+
+// NewCassette creates empty cassette, it is written to path by Save
+func NewCassette(path string) *Cassette {
+	return &Cassette{path: path}
+}
+
+// LoadCassette reads cassette recorded earlier
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read cassette")
+	}
+	c := NewCassette(path)
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, errors.Wrapf(err, "parse cassette %s", path)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+func (c *Cassette) Path() string {
+	return c.path
+}
+
+// AllowRepeats makes replay serve the last matching interaction again when all matching ones are replayed,
+// by default each recorded interaction is replayed once and extra requests are unmatched
+func (c *Cassette) AllowRepeats() *Cassette {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.repeats = true
+	return c
+}
+
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Save writes cassette to its path, parent directories are created
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "marshal cassette")
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return errors.Wrap(err, "create cassette dir")
+	}
+	return errors.Wrap(ioutil.WriteFile(c.path, append(data, '\n'), 0644), "write cassette")
+}
+
+func (c *Cassette) record(interaction Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+}
+
+// returns the first not yet replayed interaction matching request,
+// if all matching interactions are replayed the last of them is repeated only if repeats are allowed
+func (c *Cassette) match(request Interaction) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last := -1
+	for i, interaction := range c.interactions {
+		if !sameRequest(interaction, request) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return interaction, true
+		}
+		last = i
+	}
+	if last < 0 || !c.repeats {
+		return Interaction{}, false
+	}
+	return c.interactions[last], true
+}
+
+func sameRequest(a, b Interaction) bool {
+	return a.Kind == b.Kind && a.Method == b.Method && a.Path == b.Path && sameBody(a.Request, b.Request)
+}
+
+// json bodies are compared regardless of formatting and keys order
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var aValue, bValue interface{}
+	if json.Unmarshal([]byte(a), &aValue) != nil || json.Unmarshal([]byte(b), &bValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// json bodies are stored compacted to keep cassettes diff friendly
+func bodyString(body []byte) string {
+	compacted := bytes.Buffer{}
+	if json.Compact(&compacted, body) == nil {
+		return compacted.String()
+	}
+	return string(body)
+}
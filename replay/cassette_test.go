@@ -0,0 +1,121 @@
+package replay
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSameBody(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{name: "equal strings", a: "plain", b: "plain", want: true},
+		{name: "both empty", a: "", b: "", want: true},
+		{name: "different strings", a: "plain", b: "other", want: false},
+		{name: "formatting", a: `{"a":1,"b":[1,2]}`, b: "{\n  \"a\": 1,\n  \"b\": [1, 2]\n}", want: true},
+		{name: "keys order", a: `{"a":1,"b":2}`, b: `{"b":2,"a":1}`, want: true},
+		{name: "different values", a: `{"a":1}`, b: `{"a":2}`, want: false},
+		{name: "array order", a: `[1,2]`, b: `[2,1]`, want: false},
+		{name: "json and not json", a: `{"a":1}`, b: `{"a":1`, want: false},
+		{name: "empty and json", a: "", b: `{}`, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, sameBody(test.a, test.b))
+		})
+	}
+}
+
+func TestCassette_Match(t *testing.T) {
+	interactions := []Interaction{
+		{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":1}`, Response: `"first"`},
+		{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":1}`, Response: `"second"`},
+		{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":2}`, Response: `"other"`},
+		{Kind: KindHttp, Method: "GET", Path: "/items?id=1", Status: 200, Response: `"http"`},
+	}
+	tests := []struct {
+		name     string
+		repeats  bool
+		requests []Interaction
+		want     []string
+	}{
+		{
+			name: "recorded order",
+			requests: []Interaction{
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{ "id": 1 }`},
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":1}`},
+			},
+			want: []string{`"first"`, `"second"`},
+		},
+		{
+			name: "each interaction is replayed once",
+			requests: []Interaction{
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":2}`},
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":2}`},
+			},
+			want: []string{`"other"`, ""},
+		},
+		{
+			name:    "last interaction is repeated if allowed",
+			repeats: true,
+			requests: []Interaction{
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":1}`},
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":1}`},
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":1}`},
+			},
+			want: []string{`"first"`, `"second"`, `"second"`},
+		},
+		{
+			name: "kind, method, path and body must match",
+			requests: []Interaction{
+				{Kind: KindHttp, Method: "module/item/get", Request: `{"id":1}`},
+				{Kind: KindGrpc, Method: "module/item/list", Request: `{"id":1}`},
+				{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":3}`},
+				{Kind: KindHttp, Method: "GET", Path: "/items?id=2"},
+				{Kind: KindHttp, Method: "GET", Path: "/items?id=1"},
+			},
+			want: []string{"", "", "", "", `"http"`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cassette := loadCassette(t, interactions)
+			if test.repeats {
+				cassette.AllowRepeats()
+			}
+			for i, request := range test.requests {
+				interaction, ok := cassette.match(request)
+				assert.Equal(t, test.want[i] != "", ok, "request %s", request)
+				assert.Equal(t, test.want[i], interaction.Response, "request %s", request)
+			}
+		})
+	}
+}
+
+func TestCassette_RecordedInteractionsAreNotReplayed(t *testing.T) {
+	cassette := NewCassette(filepath.Join(t.TempDir(), "cassette.json"))
+	request := Interaction{Kind: KindGrpc, Method: "module/item/get", Request: `{"id":1}`}
+	cassette.record(request)
+
+	_, ok := cassette.match(request)
+	assert.False(t, ok)
+}
+
+// saves interactions and loads them back as a cassette recorded earlier
+func loadCassette(t *testing.T, interactions []Interaction) *Cassette {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorded := NewCassette(path)
+	for _, interaction := range interactions {
+		recorded.record(interaction)
+	}
+	require.NoError(t, recorded.Save())
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	return cassette
+}
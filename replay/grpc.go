@@ -0,0 +1,263 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/integration-system/isp-lib-test/mockmodule"
+	"github.com/integration-system/isp-lib-test/utils/config"
+	"github.com/integration-system/isp-lib/v2/backend"
+	"github.com/integration-system/isp-lib/v2/isp"
+	"github.com/integration-system/isp-lib/v2/structure"
+	ispUtils "github.com/integration-system/isp-lib/v2/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcProxy sits between module under test and isp dependency module
+// in record mode it forwards calls to dependency and records them into cassette, cassette is saved on Close
+// in replay mode it serves recorded responses and fails calls which have no recorded interaction,
+// each interaction is replayed once unless Cassette.AllowRepeats is set
+//
+// module under test reaches proxy by Address or through config-service routing, see RegisterModule
+type GrpcProxy struct {
+	unmatchedJournal
+
+	mode     Mode
+	cassette *Cassette
+	upstream *grpc.ClientConn
+	client   isp.BackendServiceClient
+
+	listener net.Listener
+	server   *grpc.Server
+	module   *mockmodule.Module
+}
+
+// NewGrpcProxy starts proxy on random port of host, upstream is ignored in replay mode
+// use bridge address as host to make proxy reachable from containers
+func NewGrpcProxy(host string, upstream structure.AddressConfiguration, cassette *Cassette, mode Mode) (*GrpcProxy, error) {
+	p := &GrpcProxy{mode: mode, cassette: cassette}
+	if mode == ModeRecord {
+		conn, err := grpc.Dial(upstream.GetAddress(), grpc.WithInsecure())
+		if err != nil {
+			return nil, errors.Wrap(err, "dial upstream")
+		}
+		p.upstream = conn
+		p.client = isp.NewBackendServiceClient(conn)
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		p.closeUpstream()
+		return nil, errors.Wrap(err, "listen grpc proxy")
+	}
+	p.listener = listener
+	p.server = grpc.NewServer()
+	isp.RegisterBackendServiceServer(p.server, p)
+	go func() {
+		_ = p.server.Serve(listener)
+	}()
+	return p, nil
+}
+
+func (p *GrpcProxy) Mode() Mode {
+	return p.mode
+}
+
+func (p *GrpcProxy) Cassette() *Cassette {
+	return p.cassette
+}
+
+// Address returns address of proxy, pass it to module under test instead of dependency address
+func (p *GrpcProxy) Address() structure.AddressConfiguration {
+	host, port, _ := net.SplitHostPort(p.listener.Addr().String())
+	return structure.AddressConfiguration{IP: host, Port: port}
+}
+
+// UpstreamModuleName returns module name the real dependency is connected to config-service with in record mode,
+// so config-service sends address of proxy only to modules requiring dependency by moduleName
+func UpstreamModuleName(moduleName string) string {
+	return moduleName + "-upstream"
+}
+
+// RegisterModule registers proxy in config-service as dependency module,
+// so module under test reaches it through config-service routing
+// in replay mode proxy declares recorded endpoints,
+// in record mode proxy declares endpoints of dependency connected as UpstreamModuleName(moduleName)
+// and forwards calls to upstream address
+func (p *GrpcProxy) RegisterModule(moduleName string, configAddr structure.AddressConfiguration, timeout time.Duration) error {
+	module := mockmodule.New(moduleName)
+	if p.mode == ModeReplay {
+		declared := make(map[string]bool)
+		for _, interaction := range p.cassette.Interactions() {
+			if interaction.Kind != KindGrpc || declared[interaction.Method] {
+				continue
+			}
+			declared[interaction.Method] = true
+			method := interaction.Method
+			module.Handle(method, func(req json.RawMessage) (json.RawMessage, error) {
+				return p.replay(method, req)
+			})
+		}
+	} else {
+		endpoints, err := upstreamEndpoints(UpstreamModuleName(moduleName), configAddr, timeout)
+		if err != nil {
+			return err
+		}
+		for _, endpoint := range endpoints {
+			method := endpoint.Path
+			module.Handle(method, func(ctx context.Context, md metadata.MD, req json.RawMessage) (json.RawMessage, error) {
+				resp, err := p.record(ctx, method, md, &isp.Message{Body: &isp.Message_BytesBody{BytesBody: req}})
+				if err != nil {
+					return nil, err
+				}
+				return messageBody(resp)
+			})
+		}
+	}
+	host, _, _ := net.SplitHostPort(p.listener.Addr().String())
+	if err := module.Start(host, configAddr, timeout); err != nil {
+		return err
+	}
+	p.module = module
+	return nil
+}
+
+// returns endpoints declared by connected module
+func upstreamEndpoints(moduleName string, configAddr structure.AddressConfiguration, timeout time.Duration) ([]structure.EndpointDescriptor, error) {
+	configCli, err := config.Connect(configAddr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer configCli.Raw().Close()
+	declaration, err := configCli.WaitModuleReady(moduleName, timeout)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wait upstream module")
+	}
+	return declaration.Endpoints, nil
+}
+
+// Close stops proxy, in record mode saves cassette
+func (p *GrpcProxy) Close() error {
+	if p.module != nil {
+		_ = p.module.Close()
+	}
+	p.server.Stop()
+	p.closeUpstream()
+	if p.mode == ModeRecord {
+		return p.cassette.Save()
+	}
+	return nil
+}
+
+func (p *GrpcProxy) closeUpstream() {
+	if p.upstream != nil {
+		_ = p.upstream.Close()
+	}
+}
+
+func (p *GrpcProxy) Request(ctx context.Context, msg *isp.Message) (*isp.Message, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	method := ""
+	if values := md.Get(ispUtils.ProxyMethodNameHeader); len(values) > 0 {
+		method = values[0]
+	}
+	body, err := messageBody(msg)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if p.mode == ModeReplay {
+		resp, err := p.replay(method, body)
+		if err != nil {
+			return nil, err
+		}
+		return &isp.Message{Body: &isp.Message_BytesBody{BytesBody: resp}}, nil
+	}
+
+	return p.record(ctx, method, md, msg)
+}
+
+// forwards call to upstream and records it
+func (p *GrpcProxy) record(ctx context.Context, method string, md metadata.MD, msg *isp.Message) (*isp.Message, error) {
+	body, err := messageBody(msg)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, callErr := p.client.Request(metadata.NewOutgoingContext(ctx, forwardedMetadata(md)), msg)
+	interaction := Interaction{Kind: KindGrpc, Method: method, Request: requestBody(body)}
+	if callErr != nil {
+		st := status.Convert(callErr)
+		interaction.Code = uint32(st.Code())
+		interaction.Message = st.Message()
+	} else {
+		respBody, err := messageBody(resp)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		interaction.Response = bodyString(respBody)
+	}
+	p.cassette.record(interaction)
+	return resp, callErr
+}
+
+func (p *GrpcProxy) RequestStream(isp.BackendService_RequestStreamServer) error {
+	return status.Error(codes.Unimplemented, "streams are not supported by recording proxy")
+}
+
+func (p *GrpcProxy) replay(method string, body []byte) ([]byte, error) {
+	request := Interaction{Kind: KindGrpc, Method: method, Request: requestBody(body)}
+	interaction, ok := p.cassette.match(request)
+	if !ok {
+		p.addUnmatched(request)
+		return nil, status.Errorf(codes.Unimplemented, "no recorded interaction for %s in cassette %s",
+			request, p.cassette.Path())
+	}
+	if codes.Code(interaction.Code) != codes.OK {
+		return nil, status.Error(codes.Code(interaction.Code), interaction.Message)
+	}
+	return []byte(interaction.Response), nil
+}
+
+// isp modules send json bytes, struct and list bodies are converted to json
+func messageBody(msg *isp.Message) ([]byte, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	if body := msg.GetBytesBody(); body != nil {
+		return body, nil
+	}
+	if msg.GetStructBody() == nil && msg.GetListBody() == nil {
+		return nil, nil
+	}
+	var value interface{}
+	if err := ispUtils.ConvertGrpcToGo(backend.ResolveBody(msg), &value); err != nil {
+		return nil, errors.Wrap(err, "convert message body")
+	}
+	return json.Marshal(value)
+}
+
+// empty and null requests are the same for isp modules
+func requestBody(body []byte) string {
+	result := bodyString(body)
+	if result == "null" {
+		return ""
+	}
+	return result
+}
+
+// transport headers are set by grpc client itself
+func forwardedMetadata(md metadata.MD) metadata.MD {
+	result := metadata.MD{}
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") || key == "content-type" || key == "user-agent" {
+			continue
+		}
+		result[key] = values
+	}
+	return result
+}
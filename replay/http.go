@@ -0,0 +1,157 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/integration-system/isp-lib/v2/structure"
+	"github.com/pkg/errors"
+)
+
+const defaultUpstreamTimeout = 30 * time.Second
+
+// response headers stored in cassette, others are dropped to keep cassettes free of secrets and dates
+var recordedHeaders = []string{"Content-Type"}
+
+// HttpProxy sits between module under test and third-party HTTP API
+// in record mode it forwards requests to upstream and records them into cassette, cassette is saved on Close
+// in replay mode it serves recorded responses and responds 404 to requests which have no recorded interaction,
+// each interaction is replayed once unless Cassette.AllowRepeats is set
+type HttpProxy struct {
+	unmatchedJournal
+
+	mode     Mode
+	cassette *Cassette
+	upstream string
+	client   *http.Client
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewHttpProxy starts proxy on random port of host, upstreamUrl is ignored in replay mode
+// use bridge address as host to make proxy reachable from containers
+func NewHttpProxy(host string, upstreamUrl string, cassette *Cassette, mode Mode) (*HttpProxy, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, errors.Wrap(err, "listen http proxy")
+	}
+	p := &HttpProxy{
+		mode:     mode,
+		cassette: cassette,
+		upstream: strings.TrimSuffix(upstreamUrl, "/"),
+		client:   &http.Client{Timeout: defaultUpstreamTimeout},
+		listener: listener,
+	}
+	p.server = &http.Server{Handler: p}
+	go func() {
+		_ = p.server.Serve(listener)
+	}()
+	return p, nil
+}
+
+func (p *HttpProxy) Mode() Mode {
+	return p.mode
+}
+
+func (p *HttpProxy) Cassette() *Cassette {
+	return p.cassette
+}
+
+// returns base url of proxy, pass it to module under test instead of upstream url
+func (p *HttpProxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+func (p *HttpProxy) Address() structure.AddressConfiguration {
+	host, port, _ := net.SplitHostPort(p.listener.Addr().String())
+	return structure.AddressConfiguration{IP: host, Port: port}
+}
+
+// Close stops proxy, in record mode saves cassette
+func (p *HttpProxy) Close() error {
+	err := errors.Wrap(p.server.Close(), "close http proxy")
+	if p.mode == ModeRecord {
+		if saveErr := p.cassette.Save(); saveErr != nil {
+			if err != nil {
+				return errors.Errorf("%v; %v", err, saveErr)
+			}
+			return saveErr
+		}
+	}
+	return err
+}
+
+func (p *HttpProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	request := Interaction{
+		Kind:    KindHttp,
+		Method:  r.Method,
+		Path:    r.URL.RequestURI(),
+		Request: bodyString(body),
+	}
+	if p.mode == ModeReplay {
+		p.replay(w, request)
+		return
+	}
+
+	interaction, err := p.forward(r, request, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	p.cassette.record(interaction)
+	writeResponse(w, interaction)
+}
+
+func (p *HttpProxy) forward(r *http.Request, interaction Interaction, body []byte) (Interaction, error) {
+	req, err := http.NewRequest(r.Method, p.upstream+interaction.Path, bytes.NewReader(body))
+	if err != nil {
+		return interaction, errors.Wrap(err, "create upstream request")
+	}
+	req.Header = r.Header.Clone()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return interaction, errors.Wrap(err, "upstream request")
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return interaction, errors.Wrap(err, "read upstream response")
+	}
+	interaction.Status = resp.StatusCode
+	interaction.Response = bodyString(respBody)
+	for _, header := range recordedHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			if interaction.Headers == nil {
+				interaction.Headers = make(map[string]string)
+			}
+			interaction.Headers[header] = value
+		}
+	}
+	return interaction, nil
+}
+
+func (p *HttpProxy) replay(w http.ResponseWriter, request Interaction) {
+	interaction, ok := p.cassette.match(request)
+	if !ok {
+		p.addUnmatched(request)
+		http.Error(w, fmt.Sprintf("no recorded interaction for %s in cassette %s", request, p.cassette.Path()),
+			http.StatusNotFound)
+		return
+	}
+	writeResponse(w, interaction)
+}
+
+func writeResponse(w http.ResponseWriter, interaction Interaction) {
+	for key, value := range interaction.Headers {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(interaction.Status)
+	_, _ = w.Write([]byte(interaction.Response))
+}
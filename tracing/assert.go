@@ -0,0 +1,152 @@
+package tracing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type SpanMatcher func(Span) bool
+
+func NameIs(name string) SpanMatcher {
+	return func(s Span) bool {
+		return s.Name == name
+	}
+}
+
+func ServiceIs(service string) SpanMatcher {
+	return func(s Span) bool {
+		return s.Service == service
+	}
+}
+
+func InTrace(traceId string) SpanMatcher {
+	return func(s Span) bool {
+		return s.TraceId == traceId
+	}
+}
+
+func HasAttribute(key string, value interface{}) SpanMatcher {
+	return func(s Span) bool {
+		v, ok := s.Attributes[key]
+		return ok && fmt.Sprint(v) == fmt.Sprint(value)
+	}
+}
+
+func IsError() SpanMatcher {
+	return func(s Span) bool {
+		return s.Error
+	}
+}
+
+func matchAll(span Span, matchers []SpanMatcher) bool {
+	for _, m := range matchers {
+		if !m(span) {
+			return false
+		}
+	}
+	return true
+}
+
+// AssertSpan fails t if there is no received span matching all matchers
+func (c *Collector) AssertSpan(t assert.TestingT, matchers ...SpanMatcher) bool {
+	if len(c.FindSpans(matchers...)) > 0 {
+		return true
+	}
+	return assert.Fail(t, "expected span not found", "received spans:\n%s", formatSpans(c.Spans()))
+}
+
+// AssertChildSpan fails t if there is no span named name whose parent span belongs to parentService
+func (c *Collector) AssertChildSpan(t assert.TestingT, name, parentService string) bool {
+	spans := c.Spans()
+	byId := indexSpans(spans)
+	for _, span := range spans {
+		if span.Name != name {
+			continue
+		}
+		if parent, ok := byId[span.ParentSpanId]; ok && parent.Service == parentService {
+			return true
+		}
+	}
+	return assert.Fail(t, fmt.Sprintf("span %s with parent from %s not found", name, parentService),
+		"received spans:\n%s", formatSpans(spans))
+}
+
+// AssertTracePath fails t if trace does not pass through services in specified order,
+// e.g. A -> B -> C means span of C has ancestor span of B which has ancestor span of A
+func (c *Collector) AssertTracePath(t assert.TestingT, traceId string, services ...string) bool {
+	spans := c.Trace(traceId)
+	if len(spans) == 0 {
+		return assert.Fail(t, fmt.Sprintf("trace %s not received", traceId))
+	}
+	byId := indexSpans(spans)
+	for _, span := range spans {
+		if tracePath(span, byId, services) {
+			return true
+		}
+	}
+	return assert.Fail(t, fmt.Sprintf("trace %s does not span %s", traceId, strings.Join(services, " -> ")),
+		"trace spans:\n%s", formatSpans(spans))
+}
+
+// WaitTracePath waits until trace passes through services in specified order, see AssertTracePath
+func (c *Collector) WaitTracePath(traceId string, timeout time.Duration, services ...string) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		spans := c.Trace(traceId)
+		byId := indexSpans(spans)
+		for _, span := range spans {
+			if tracePath(span, byId, services) {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("trace %s does not span %s after %s", traceId, strings.Join(services, " -> "), timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// walks from span to root and checks that services appear along the way in reverse order
+func tracePath(span Span, byId map[string]Span, services []string) bool {
+	i := len(services) - 1
+	if i < 0 {
+		return true
+	}
+	if span.Service != services[i] {
+		return false
+	}
+	current := span
+	// broken traces may contain cycles or spans referencing themselves as parent
+	visited := map[string]bool{span.SpanId: true}
+	for i > 0 {
+		parent, ok := byId[current.ParentSpanId]
+		if !ok || visited[parent.SpanId] {
+			return false
+		}
+		visited[parent.SpanId] = true
+		if parent.Service == services[i-1] {
+			i--
+		}
+		current = parent
+	}
+	return true
+}
+
+func indexSpans(spans []Span) map[string]Span {
+	result := make(map[string]Span, len(spans))
+	for _, span := range spans {
+		result[span.SpanId] = span
+	}
+	return result
+}
+
+func formatSpans(spans []Span) string {
+	lines := make([]string, 0, len(spans))
+	for _, span := range spans {
+		lines = append(lines, span.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,358 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	serviceNameAttribute = "service.name"
+	tracesPath           = "/v1/traces"
+)
+
+// Span is a received span in readable form
+type Span struct {
+	TraceId      string
+	SpanId       string
+	ParentSpanId string
+	Name         string
+	// value of service.name resource attribute
+	Service    string
+	Kind       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Error      bool
+}
+
+func (s Span) IsRoot() bool {
+	return s.ParentSpanId == ""
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s/%s %s (trace=%s parent=%s)", s.Service, s.Name, s.SpanId, s.TraceId, s.ParentSpanId)
+}
+
+// Collector is in-process OTLP receiver, it accepts traces over gRPC and HTTP and keeps spans in memory
+type Collector struct {
+	collectortrace.UnimplementedTraceServiceServer
+
+	grpcListener net.Listener
+	httpListener net.Listener
+	grpcServer   *grpc.Server
+	httpServer   *http.Server
+
+	mu      sync.Mutex
+	spans   []Span
+	waiters map[int]func(Span)
+	nextId  int
+}
+
+// NewCollector starts OTLP gRPC and HTTP receivers on random ports of host
+// use bridge address as host to make receiver reachable from containers
+func NewCollector(host string) (*Collector, error) {
+	grpcListener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, errors.Wrap(err, "listen otlp grpc")
+	}
+	httpListener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		_ = grpcListener.Close()
+		return nil, errors.Wrap(err, "listen otlp http")
+	}
+
+	c := &Collector{
+		grpcListener: grpcListener,
+		httpListener: httpListener,
+		grpcServer:   grpc.NewServer(),
+		waiters:      make(map[int]func(Span)),
+	}
+	collectortrace.RegisterTraceServiceServer(c.grpcServer, c)
+	mux := http.NewServeMux()
+	mux.HandleFunc(tracesPath, c.handleHttp)
+	c.httpServer = &http.Server{Handler: mux}
+
+	go func() {
+		_ = c.grpcServer.Serve(grpcListener)
+	}()
+	go func() {
+		_ = c.httpServer.Serve(httpListener)
+	}()
+	return c, nil
+}
+
+// returns host:port of OTLP gRPC receiver
+func (c *Collector) GrpcAddress() string {
+	return c.grpcListener.Addr().String()
+}
+
+// returns base url of OTLP HTTP receiver, exporters append /v1/traces
+func (c *Collector) HttpEndpoint() string {
+	return "http://" + c.httpListener.Addr().String()
+}
+
+// returns standard OpenTelemetry SDK env variables pointing exporter to collector
+func (c *Collector) ExporterEnv(serviceName string) map[string]string {
+	return map[string]string{
+		"OTEL_SERVICE_NAME":                  serviceName,
+		"OTEL_TRACES_EXPORTER":               "otlp",
+		"OTEL_EXPORTER_OTLP_PROTOCOL":        "grpc",
+		"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://" + c.GrpcAddress(),
+		"OTEL_EXPORTER_OTLP_INSECURE":        "true",
+		"OTEL_EXPORTER_OTLP_TRACES_INSECURE": "true",
+	}
+}
+
+func (c *Collector) Close() error {
+	c.grpcServer.Stop()
+	return c.httpServer.Close()
+}
+
+func (c *Collector) Export(_ context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	c.add(req.GetResourceSpans())
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+func (c *Collector) handleHttp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &collectortrace.ExportTraceServiceRequest{}
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/json") {
+		body, err = normalizeJson(body)
+		if err == nil {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+		}
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.add(req.GetResourceSpans())
+
+	resp, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(resp)
+}
+
+// OTLP/JSON encodes trace and span ids as hex while protojson expects base64 for bytes,
+// newer exporters send scopeSpans and scope instead of instrumentationLibrarySpans and instrumentationLibrary
+func normalizeJson(body []byte) ([]byte, error) {
+	var req map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, errors.Wrap(err, "parse otlp json")
+	}
+	for _, rs := range objects(req["resourceSpans"]) {
+		renameKey(rs, "scopeSpans", "instrumentationLibrarySpans")
+		for _, ils := range objects(rs["instrumentationLibrarySpans"]) {
+			renameKey(ils, "scope", "instrumentationLibrary")
+			for _, span := range objects(ils["spans"]) {
+				hexToBase64(span, "traceId", 16)
+				hexToBase64(span, "spanId", 8)
+				hexToBase64(span, "parentSpanId", 8)
+				for _, link := range objects(span["links"]) {
+					hexToBase64(link, "traceId", 16)
+					hexToBase64(link, "spanId", 8)
+				}
+			}
+		}
+	}
+	return json.Marshal(req)
+}
+
+func objects(value interface{}) []map[string]interface{} {
+	list, _ := value.([]interface{})
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func renameKey(m map[string]interface{}, from, to string) {
+	if value, ok := m[from]; ok {
+		if _, exists := m[to]; !exists {
+			m[to] = value
+		}
+		delete(m, from)
+	}
+}
+
+// ids of other length are left as is, they are expected to be base64 already
+func hexToBase64(m map[string]interface{}, key string, size int) {
+	value, ok := m[key].(string)
+	if !ok || len(value) != size*2 {
+		return
+	}
+	if id, err := hex.DecodeString(value); err == nil {
+		m[key] = base64.StdEncoding.EncodeToString(id)
+	}
+}
+
+func (c *Collector) add(resourceSpans []*tracev1.ResourceSpans) {
+	spans := make([]Span, 0)
+	for _, rs := range resourceSpans {
+		service := ""
+		for _, attr := range rs.GetResource().GetAttributes() {
+			if attr.GetKey() == serviceNameAttribute {
+				service = attr.GetValue().GetStringValue()
+			}
+		}
+		for _, ils := range rs.GetInstrumentationLibrarySpans() {
+			for _, s := range ils.GetSpans() {
+				spans = append(spans, convertSpan(service, s))
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans = append(c.spans, spans...)
+	for _, span := range spans {
+		for _, f := range c.waiters {
+			f(span)
+		}
+	}
+}
+
+// Spans returns all received spans
+func (c *Collector) Spans() []Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Span(nil), c.spans...)
+}
+
+// Reset removes all received spans
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans = nil
+}
+
+// FindSpans returns received spans matching all matchers
+func (c *Collector) FindSpans(matchers ...SpanMatcher) []Span {
+	result := make([]Span, 0)
+	for _, span := range c.Spans() {
+		if matchAll(span, matchers) {
+			result = append(result, span)
+		}
+	}
+	return result
+}
+
+// Trace returns all received spans of trace
+func (c *Collector) Trace(traceId string) []Span {
+	return c.FindSpans(InTrace(traceId))
+}
+
+// WaitSpan waits until span matching all matchers is received
+func (c *Collector) WaitSpan(timeout time.Duration, matchers ...SpanMatcher) (Span, error) {
+	found := make(chan Span, 1)
+	c.mu.Lock()
+	id := c.nextId
+	c.nextId++
+	c.waiters[id] = func(span Span) {
+		if matchAll(span, matchers) {
+			select {
+			case found <- span:
+			default:
+			}
+		}
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.waiters, id)
+		c.mu.Unlock()
+	}()
+
+	if spans := c.FindSpans(matchers...); len(spans) > 0 {
+		return spans[0], nil
+	}
+	select {
+	case span := <-found:
+		return span, nil
+	case <-time.After(timeout):
+		return Span{}, errors.Errorf("expected span not received after %s", timeout)
+	}
+}
+
+func convertSpan(service string, s *tracev1.Span) Span {
+	span := Span{
+		TraceId:      hex.EncodeToString(s.GetTraceId()),
+		SpanId:       hex.EncodeToString(s.GetSpanId()),
+		ParentSpanId: hex.EncodeToString(s.GetParentSpanId()),
+		Name:         s.GetName(),
+		Service:      service,
+		Kind:         strings.TrimPrefix(s.GetKind().String(), "SPAN_KIND_"),
+		Start:        time.Unix(0, int64(s.GetStartTimeUnixNano())),
+		End:          time.Unix(0, int64(s.GetEndTimeUnixNano())),
+		Attributes:   make(map[string]interface{}, len(s.GetAttributes())),
+		Error:        s.GetStatus().GetCode() == tracev1.Status_STATUS_CODE_ERROR,
+	}
+	for _, attr := range s.GetAttributes() {
+		span.Attributes[attr.GetKey()] = anyValue(attr.GetValue())
+	}
+	return span
+}
+
+func anyValue(v *commonv1.AnyValue) interface{} {
+	switch value := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return value.StringValue
+	case *commonv1.AnyValue_BoolValue:
+		return value.BoolValue
+	case *commonv1.AnyValue_IntValue:
+		return value.IntValue
+	case *commonv1.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *commonv1.AnyValue_BytesValue:
+		return value.BytesValue
+	case *commonv1.AnyValue_ArrayValue:
+		result := make([]interface{}, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			result = append(result, anyValue(item))
+		}
+		return result
+	case *commonv1.AnyValue_KvlistValue:
+		result := make(map[string]interface{}, len(value.KvlistValue.GetValues()))
+		for _, kv := range value.KvlistValue.GetValues() {
+			result[kv.GetKey()] = anyValue(kv.GetValue())
+		}
+		return result
+	default:
+		return nil
+	}
+}
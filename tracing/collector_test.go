@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// payload in OTLP/JSON format as sent by OpenTelemetry SDK exporters
+const otlpJsonPayload = `{
+  "resourceSpans": [
+    {
+      "resource": {
+        "attributes": [
+          {"key": "service.name", "value": {"stringValue": "isp-gate"}}
+        ]
+      },
+      "scopeSpans": [
+        {
+          "scope": {"name": "isp-lib", "version": "1.0.0"},
+          "spans": [
+            {
+              "traceId": "5b8efff798038103d269b633813fc60c",
+              "spanId": "eee19b7ec3c1b174",
+              "name": "GET /api/items",
+              "kind": 2,
+              "startTimeUnixNano": "1544712660000000000",
+              "endTimeUnixNano": "1544712661000000000",
+              "flags": 1,
+              "attributes": [
+                {"key": "http.status_code", "value": {"intValue": "200"}}
+              ]
+            },
+            {
+              "traceId": "5b8efff798038103d269b633813fc60c",
+              "spanId": "eee19b7ec3c1b173",
+              "parentSpanId": "eee19b7ec3c1b174",
+              "name": "items/list",
+              "kind": 3,
+              "startTimeUnixNano": "1544712660100000000",
+              "endTimeUnixNano": "1544712660900000000",
+              "links": [
+                {"traceId": "5b8efff798038103d269b633813fc60d", "spanId": "eee19b7ec3c1b175"}
+              ],
+              "status": {"code": 2, "message": "failed"}
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`
+
+func TestCollector_HttpJson(t *testing.T) {
+	collector, err := NewCollector("127.0.0.1")
+	require.NoError(t, err)
+	defer collector.Close()
+
+	resp, err := http.Post(collector.HttpEndpoint()+tracesPath, "application/json", strings.NewReader(otlpJsonPayload))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := collector.Trace("5b8efff798038103d269b633813fc60c")
+	require.Len(t, spans, 2)
+	root := spans[0]
+	assert.True(t, root.IsRoot())
+	assert.Equal(t, "eee19b7ec3c1b174", root.SpanId)
+	assert.Equal(t, "isp-gate", root.Service)
+	assert.Equal(t, "SERVER", root.Kind)
+	assert.Equal(t, int64(200), root.Attributes["http.status_code"])
+
+	child := spans[1]
+	assert.Equal(t, "eee19b7ec3c1b173", child.SpanId)
+	assert.Equal(t, "eee19b7ec3c1b174", child.ParentSpanId)
+	assert.Equal(t, "CLIENT", child.Kind)
+	assert.True(t, child.Error)
+}
+
+func TestCollector_HttpJsonBase64Ids(t *testing.T) {
+	payload := `{"resourceSpans": [{"instrumentationLibrarySpans": [{"spans": [
+		{"traceId": "W47/95gDgQPSabYzgT/GDA==", "spanId": "7uGbfsPBsXQ=", "name": "legacy"}
+	]}]}]}`
+	body, err := normalizeJson([]byte(payload))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"traceId":"W47/95gDgQPSabYzgT/GDA=="`)
+	assert.Contains(t, string(body), `"spanId":"7uGbfsPBsXQ="`)
+}
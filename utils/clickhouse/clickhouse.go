@@ -0,0 +1,169 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/ClickHouse/clickhouse-go"
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/integration-system/isp-lib/v2/structure"
+)
+
+// returns native protocol dsn
+func DSN(cfg structure.DBConfiguration) string {
+	params := url.Values{}
+	params.Set("username", cfg.Username)
+	params.Set("password", cfg.Password)
+	if cfg.Database != "" {
+		params.Set("database", cfg.Database)
+	}
+	return fmt.Sprintf("tcp://%s:%s?%s", cfg.Address, cfg.Port, params.Encode())
+}
+
+func Wait(cfg structure.DBConfiguration, timeout time.Duration) (*sql.DB, error) {
+	db, err := sql.Open("clickhouse", DSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+	_, err = utils.AwaitConnection(func() (interface{}, error) {
+		return nil, db.Ping()
+	}, timeout)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func CreateDatabase(db *sql.DB, database string) error {
+	_, err := db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", QuoteIdentifier(database)))
+	return err
+}
+
+// QuoteIdentifier quotes name with backticks, e.g. database or table name
+func QuoteIdentifier(name string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "`", "\\`")
+	return "`" + replacer.Replace(name) + "`"
+}
+
+// execute statements separated by semicolon one by one
+// semicolons inside quoted strings, identifiers and comments do not split statements
+func ExecScript(db *sql.DB, script string) error {
+	for _, stmt := range SplitStatements(script) {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitStatements splits sql script by semicolons outside of quotes and comments,
+// statements consisting of whitespaces and comments only are omitted
+func SplitStatements(script string) []string {
+	result := make([]string, 0)
+	start := 0
+	meaningful := false
+	add := func(end int) {
+		if meaningful {
+			result = append(result, strings.TrimSpace(script[start:end]))
+		}
+		start = end + 1
+		meaningful = false
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			meaningful = true
+			// backslash escapes and doubled quotes are both skipped
+			for i++; i < len(script); i++ {
+				if script[i] == '\\' {
+					i++
+				} else if script[i] == c {
+					if i+1 < len(script) && script[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == ';':
+			add(i)
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			meaningful = true
+		}
+	}
+	add(len(script))
+	return result
+}
+
+// insert rows in single batch, clickhouse driver requires transaction for batch insert
+func LoadFixtures(db *sql.DB, table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// returns rows as column name -> value maps
+func Query(db *sql.DB, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,66 @@
+package clickhouse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "empty",
+			script: " \n\t",
+			want:   []string{},
+		},
+		{
+			name:   "separated by semicolons",
+			script: "CREATE TABLE a (x UInt8) ENGINE = Memory;\nINSERT INTO a VALUES (1);",
+			want:   []string{"CREATE TABLE a (x UInt8) ENGINE = Memory", "INSERT INTO a VALUES (1)"},
+		},
+		{
+			name:   "trailing statement without semicolon",
+			script: "SELECT 1;\nSELECT 2\n",
+			want:   []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:   "semicolon in quotes",
+			script: "INSERT INTO a VALUES ('a;b');SELECT \"c;d\" FROM `e;f`",
+			want:   []string{"INSERT INTO a VALUES ('a;b')", "SELECT \"c;d\" FROM `e;f`"},
+		},
+		{
+			name:   "backslash escaped quote",
+			script: `SELECT 'it\'s;'; SELECT 'a\\';SELECT 2`,
+			want:   []string{`SELECT 'it\'s;'`, `SELECT 'a\\'`, "SELECT 2"},
+		},
+		{
+			name:   "doubled quote",
+			script: "SELECT 'it''s;';SELECT 2",
+			want:   []string{"SELECT 'it''s;'", "SELECT 2"},
+		},
+		{
+			name:   "line comment",
+			script: "-- first; statement\nSELECT 1; -- trailing; comment\nSELECT 2",
+			want:   []string{"-- first; statement\nSELECT 1", "-- trailing; comment\nSELECT 2"},
+		},
+		{
+			name:   "block comment",
+			script: "/* a; b */ SELECT 1 /* c; */;SELECT 2",
+			want:   []string{"/* a; b */ SELECT 1 /* c; */", "SELECT 2"},
+		},
+		{
+			name:   "comment only statements are omitted",
+			script: "SELECT 1;\n-- done;\n/* nothing; */;\n-- end",
+			want:   []string{"SELECT 1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, SplitStatements(test.script))
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"time"
+
+	"github.com/integration-system/isp-lib/v2/backend"
+	"github.com/integration-system/isp-lib/v2/structure"
+	"github.com/pkg/errors"
+)
+
+const (
+	getModulesCommand          = "config/module/get_modules_info"
+	getActiveConfigCommand     = "config/config/get_active_config_by_module_name"
+	getConfigsByModuleCommand  = "config/config/get_configs_by_module_id"
+	upsertConfigCommand        = "config/config/create_update_config"
+	markConfigAsActiveCommand  = "config/config/mark_config_as_active"
+	deleteConfigsCommand       = "config/config/delete_config"
+	getCommonConfigsCommand    = "config/common_config/get_configs"
+	upsertCommonConfigCommand  = "config/common_config/create_update_config"
+	getSchemaByModuleIdCommand = "config/schema/get_by_module_id"
+
+	adminCallerId             = -1
+	defaultAdminInvokeTimeout = 10 * time.Second
+)
+
+// Client is typed client of config-service admin api
+type Client struct {
+	rx *backend.RxGrpcClient
+}
+
+func NewClient(rx *backend.RxGrpcClient) *Client {
+	return &Client{rx: rx}
+}
+
+// Connect waits for config-service and returns typed client
+func Connect(configAddr structure.AddressConfiguration, timeout time.Duration) (*Client, error) {
+	rx, err := Wait(configAddr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(rx), nil
+}
+
+// Raw returns underlying grpc client
+func (c *Client) Raw() *backend.RxGrpcClient {
+	return c.rx
+}
+
+func (c *Client) invoke(method string, req, resp interface{}) error {
+	err := c.rx.Invoke(method, adminCallerId, req, resp, backend.WithTimeout(defaultAdminInvokeTimeout))
+	return errors.Wrap(err, method)
+}
+
+// Modules returns all modules known by config-service with their connection status
+func (c *Client) Modules() ([]Module, error) {
+	var modules []Module
+	err := c.invoke(getModulesCommand, nil, &modules)
+	return modules, err
+}
+
+// Module returns module by name, nil if module has never been connected
+func (c *Client) Module(name string) (*Module, error) {
+	modules, err := c.Modules()
+	if err != nil {
+		return nil, err
+	}
+	for i := range modules {
+		if modules[i].Name == name {
+			return &modules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ActiveConfig returns active remote config of module
+func (c *Client) ActiveConfig(moduleName string) (*ModuleConfig, error) {
+	cfg := new(ModuleConfig)
+	err := c.invoke(getActiveConfigCommand, moduleNameRequest{ModuleName: moduleName}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Configs returns all remote configs of module
+func (c *Client) Configs(moduleId string) ([]ModuleConfig, error) {
+	var configs []ModuleConfig
+	err := c.invoke(getConfigsByModuleCommand, moduleIdRequest{ModuleId: moduleId}, &configs)
+	return configs, err
+}
+
+// CreateConfig creates new remote config of module, config is validated against module schema
+func (c *Client) CreateConfig(cfg ModuleConfig) (*ModuleConfig, error) {
+	cfg.Id = ""
+	return c.upsertConfig(cfg, false)
+}
+
+// UpdateConfig updates existing remote config, set unsafe to skip validation against module schema
+// if config is active it is sent to connected module instances
+func (c *Client) UpdateConfig(cfg ModuleConfig, unsafe bool) (*ModuleConfig, error) {
+	if cfg.Id == "" {
+		return nil, errors.New("config id is required for update")
+	}
+	return c.upsertConfig(cfg, unsafe)
+}
+
+func (c *Client) upsertConfig(cfg ModuleConfig, unsafe bool) (*ModuleConfig, error) {
+	result := new(ModuleConfig)
+	err := c.invoke(upsertConfigCommand, upsertConfigRequest{ModuleConfig: cfg, Unsafe: unsafe}, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ActivateConfig marks config as active and sends it to connected module instances
+func (c *Client) ActivateConfig(configId string) (*ModuleConfig, error) {
+	result := new(ModuleConfig)
+	err := c.invoke(markConfigAsActiveCommand, configIdRequest{Id: configId}, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteConfigs deletes remote configs, returns number of deleted configs
+func (c *Client) DeleteConfigs(configIds ...string) (int, error) {
+	resp := new(deleteResponse)
+	err := c.invoke(deleteConfigsCommand, configIds, resp)
+	return resp.Deleted, err
+}
+
+// CommonConfigs returns common configs by ids, all common configs if ids are empty
+func (c *Client) CommonConfigs(ids ...string) ([]CommonConfig, error) {
+	var configs []CommonConfig
+	err := c.invoke(getCommonConfigsCommand, commonConfigsRequest{Ids: ids}, &configs)
+	return configs, err
+}
+
+// UpsertCommonConfig creates common config if id is empty or updates existing one
+func (c *Client) UpsertCommonConfig(cfg CommonConfig) (*CommonConfig, error) {
+	result := new(CommonConfig)
+	err := c.invoke(upsertCommonConfigCommand, cfg, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteCommonConfig deletes common config, fails if config is linked to some module config
+func (c *Client) DeleteCommonConfig(id string) error {
+	var resp interface{}
+	return c.invoke(deleteCommonConfigsCommand, configIdRequest{Id: id}, &resp)
+}
+
+// Routes returns declarations of all connected module instances
+func (c *Client) Routes() (structure.RoutingConfig, error) {
+	var routes structure.RoutingConfig
+	err := c.invoke(getRoutesCommand, nil, &routes)
+	return routes, err
+}
+
+// Schema returns remote config schema published by module
+func (c *Client) Schema(moduleId string) (*ConfigSchema, error) {
+	result := new(ConfigSchema)
+	err := c.invoke(getSchemaByModuleIdCommand, moduleIdRequest{ModuleId: moduleId}, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ModuleSchema returns remote config schema published by module with specified name
+func (c *Client) ModuleSchema(moduleName string) (*ConfigSchema, error) {
+	module, err := c.Module(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if module == nil {
+		return nil, errors.Errorf("module %s not found", moduleName)
+	}
+	return c.Schema(module.Id)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"time"
 
+	"github.com/integration-system/isp-lib-test/ctx"
 	"github.com/integration-system/isp-lib-test/utils"
 	"github.com/integration-system/isp-lib/v2/backend"
 	"github.com/integration-system/isp-lib/v2/structure"
@@ -21,17 +22,20 @@ type configIdRequest struct {
 	Id string `json:"id" valid:"required~Required"`
 }
 
+// Wait waits until config-service answers admin requests
+// configAddr can contain addresses of several cluster nodes joined by ';'
 func Wait(configAddr structure.AddressConfiguration, timeout time.Duration) (*backend.RxGrpcClient, error) {
-	if configAddr.Port == configServiceHttpPort {
-		configAddr.Port = configServiceGrpcPort
+	addrs := ctx.SplitAddresses(configAddr)
+	for i := range addrs {
+		if addrs[i].Port == configServiceHttpPort {
+			addrs[i].Port = configServiceGrpcPort
+		}
 	}
 	client := backend.NewRxGrpcClient(backend.WithDialOptions(grpc.WithInsecure()))
-	client.ReceiveAddressList([]structure.AddressConfiguration{configAddr})
-	req := new(configIdRequest)
-	req.Id = "339lc5u5s4"
-	var resp interface{}
+	client.ReceiveAddressList(addrs)
 	_, err := utils.AwaitConnection(func() (interface{}, error) {
-		err := client.Invoke(deleteCommonConfigsCommand, -1, req, resp)
+		var routes structure.RoutingConfig
+		err := client.Invoke(getRoutesCommand, adminCallerId, nil, &routes)
 		return nil, err
 	}, timeout)
 	if err != nil {
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"time"
+
+	"github.com/integration-system/isp-lib/v2/structure"
+)
+
+// entities of config-service admin api
+
+type Module struct {
+	Id                 string
+	Name               string
+	Active             bool
+	CreatedAt          time.Time
+	LastConnectedAt    time.Time
+	LastDisconnectedAt time.Time
+	Configs            []ModuleConfig
+	ConfigSchema       map[string]interface{}
+	// connected instances of module, empty if module is disconnected
+	Status []ModuleConnection
+}
+
+func (m Module) Connected() bool {
+	return len(m.Status) > 0
+}
+
+type ModuleConnection struct {
+	LibVersion      string
+	Version         string
+	Address         structure.AddressConfiguration
+	Endpoints       []structure.EndpointDescriptor
+	RequiredModules []structure.ModuleDependency
+	CreatedAt       time.Time
+}
+
+type ModuleConfig struct {
+	Id            string
+	Name          string
+	Description   string
+	ModuleId      string
+	CommonConfigs []string
+	Version       int32
+	Active        bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Data          map[string]interface{}
+}
+
+type CommonConfig struct {
+	Id          string
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Data        map[string]interface{}
+}
+
+type ConfigSchema struct {
+	Id        string
+	Version   string
+	ModuleId  string
+	Schema    map[string]interface{}
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type moduleNameRequest struct {
+	ModuleName string `json:"moduleName"`
+}
+
+type moduleIdRequest struct {
+	ModuleId string `json:"moduleId"`
+}
+
+type upsertConfigRequest struct {
+	ModuleConfig
+	// skip validation against module schema
+	Unsafe bool
+}
+
+type commonConfigsRequest struct {
+	Ids []string `json:"ids"`
+}
+
+type deleteResponse struct {
+	Deleted int
+}
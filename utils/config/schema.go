@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	ispUtils "github.com/integration-system/isp-lib/v2/utils"
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// FieldError is a violation of remote config schema by a single field
+type FieldError struct {
+	Field       string
+	Description string
+	Value       interface{}
+}
+
+func (e FieldError) String() string {
+	return fmt.Sprintf("%s: %s (value: %v)", e.Field, e.Description, e.Value)
+}
+
+// ValidationError lists all fields of remote config violating the schema
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		lines = append(lines, "  "+fieldErr.String())
+	}
+	return fmt.Sprintf("remote config does not match schema:\n%s", strings.Join(lines, "\n"))
+}
+
+// LoadSchemaFile reads json schema of remote config from file
+func LoadSchemaFile(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read schema file")
+	}
+	schema := make(map[string]interface{})
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, errors.Wrapf(err, "parse schema file %s", path)
+	}
+	return schema, nil
+}
+
+// ValidateConfig validates remote config against json schema, returns *ValidationError with field-level errors
+// remoteConfig can be map or struct, structs are encoded the same way as isp modules do
+func ValidateConfig(schema map[string]interface{}, remoteConfig interface{}) error {
+	document, err := ispUtils.ConvertGoToBytes(remoteConfig)
+	if err != nil {
+		return errors.Wrap(err, "marshal remote config")
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewBytesLoader(document))
+	if err != nil {
+		return errors.Wrap(err, "validate remote config")
+	}
+	if result.Valid() {
+		return nil
+	}
+	validationErr := &ValidationError{}
+	for _, resultErr := range result.Errors() {
+		validationErr.Errors = append(validationErr.Errors, FieldError{
+			Field:       resultErr.Field(),
+			Description: resultErr.Description(),
+			Value:       resultErr.Value(),
+		})
+	}
+	sort.Slice(validationErr.Errors, func(i, j int) bool {
+		return validationErr.Errors[i].Field < validationErr.Errors[j].Field
+	})
+	return validationErr
+}
+
+// ValidateModuleConfig validates remote config against schema published by module to config-service
+func (c *Client) ValidateModuleConfig(moduleName string, remoteConfig interface{}) error {
+	schema, err := c.ModuleSchema(moduleName)
+	if err != nil {
+		return err
+	}
+	return errors.WithMessagef(ValidateConfig(schema.Schema, remoteConfig), "module %s", moduleName)
+}
+
+// DefaultConfig generates remote config from default values declared in json schema
+// objects without default values are omitted
+func DefaultConfig(schema map[string]interface{}) map[string]interface{} {
+	cfg, _ := defaultValue(schema, schema, make(map[string]bool), 0).(map[string]interface{})
+	return cfg
+}
+
+// DefaultModuleConfig generates remote config from default values of schema published by module
+func (c *Client) DefaultModuleConfig(moduleName string) (map[string]interface{}, error) {
+	schema, err := c.ModuleSchema(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	return DefaultConfig(schema.Schema), nil
+}
+
+// limits nesting of schema objects, deeper properties are omitted
+const maxSchemaDepth = 64
+
+// visiting holds references being resolved on the current path, recursive references are omitted
+func defaultValue(root, node map[string]interface{}, visiting map[string]bool, depth int) interface{} {
+	if depth > maxSchemaDepth {
+		return nil
+	}
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			break
+		}
+		if visiting[ref] {
+			return nil
+		}
+		visiting[ref] = true
+		defer delete(visiting, ref)
+		node = resolveRef(root, ref)
+		if node == nil {
+			return nil
+		}
+	}
+	if value, ok := node["default"]; ok {
+		return value
+	}
+	properties, ok := node["properties"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	result := make(map[string]interface{})
+	for name, property := range properties {
+		propertySchema, ok := property.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value := defaultValue(root, propertySchema, visiting, depth+1); value != nil {
+			result[name] = value
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// resolves local references like #/definitions/Name
+func resolveRef(root map[string]interface{}, ref string) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var node interface{} = root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[part]
+	}
+	result, _ := node.(map[string]interface{})
+	return result
+}
@@ -0,0 +1,62 @@
+package config
+
+// ConfigUpdate is a change of module active remote config which can be reverted
+type ConfigUpdate struct {
+	client   *Client
+	Original ModuleConfig
+	Updated  ModuleConfig
+}
+
+// PatchActiveConfig merges patch into active remote config of module and saves it,
+// nested maps are merged recursively, config-service sends new config to connected module instances
+func (c *Client) PatchActiveConfig(moduleName string, patch map[string]interface{}) (*ConfigUpdate, error) {
+	active, err := c.ActiveConfig(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	cfg := *active
+	cfg.Data = mergeData(copyData(active.Data), patch)
+	updated, err := c.UpdateConfig(cfg, false)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigUpdate{client: c, Original: *active, Updated: *updated}, nil
+}
+
+// Restore saves original data of updated config
+func (u *ConfigUpdate) Restore() error {
+	cfg := u.Updated
+	cfg.Data = u.Original.Data
+	_, err := u.client.UpdateConfig(cfg, true)
+	return err
+}
+
+func mergeData(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		srcMap, srcOk := v.(map[string]interface{})
+		dstMap, dstOk := dst[k].(map[string]interface{})
+		if srcOk && dstOk {
+			dst[k] = mergeData(dstMap, srcMap)
+		} else {
+			dst[k] = v
+		}
+	}
+	return dst
+}
+
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if m, ok := v.(map[string]interface{}); ok {
+			v = copyData(m)
+		}
+		result[k] = v
+	}
+	return result
+}
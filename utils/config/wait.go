@@ -0,0 +1,77 @@
+package config
+
+import (
+	"time"
+
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/integration-system/isp-lib/v2/structure"
+	"github.com/pkg/errors"
+)
+
+// WaitModuleReady waits until module is connected to config-service, has received remote config
+// and published its declaration in routes, config-service accepts declaration only after module applied remote config
+// returns the module declaration
+func (c *Client) WaitModuleReady(moduleName string, timeout time.Duration) (*structure.BackendDeclaration, error) {
+	return c.waitDeclaration(func(declaration structure.BackendDeclaration) bool {
+		return declaration.ModuleName == moduleName
+	}, func() error {
+		module, err := c.Module(moduleName)
+		if err != nil {
+			return err
+		}
+		if module == nil || !module.Connected() {
+			return errors.Errorf("module %s is not connected to config service", moduleName)
+		}
+		return nil
+	}, timeout, "module %s is not ready", moduleName)
+}
+
+// WaitRoute waits until some connected module declares endpoint with specified path
+// returns declaration of the module
+func (c *Client) WaitRoute(path string, timeout time.Duration) (*structure.BackendDeclaration, error) {
+	return c.waitDeclaration(func(declaration structure.BackendDeclaration) bool {
+		for _, endpoint := range declaration.Endpoints {
+			if endpoint.Path == path {
+				return true
+			}
+		}
+		return false
+	}, nil, timeout, "route %s is not declared", path)
+}
+
+func (c *Client) waitDeclaration(
+	match func(structure.BackendDeclaration) bool,
+	check func() error,
+	timeout time.Duration,
+	format string, args ...interface{},
+) (*structure.BackendDeclaration, error) {
+	var lastErr error
+	retryer := utils.NewRetryer(func() (interface{}, error) {
+		if check != nil {
+			if err := check(); err != nil {
+				return nil, err
+			}
+		}
+		routes, err := c.Routes()
+		if err != nil {
+			return nil, err
+		}
+		for i := range routes {
+			if match(routes[i]) {
+				return &routes[i], nil
+			}
+		}
+		return nil, errors.Errorf(format, args...)
+	}, timeout)
+	retryer.AttemptErrorHanlder = func(err error) {
+		lastErr = err
+	}
+	declaration, err := retryer.Do()
+	if err != nil {
+		if lastErr != nil {
+			err = lastErr
+		}
+		return nil, errors.Wrapf(err, "wait %s", timeout)
+	}
+	return declaration.(*structure.BackendDeclaration), nil
+}
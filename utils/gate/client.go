@@ -0,0 +1,106 @@
+package gate
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/integration-system/isp-lib/v2/structure"
+	ispUtils "github.com/integration-system/isp-lib/v2/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// gate exposes module endpoints under this prefix
+	DefaultPathPrefix = "/api/"
+
+	defaultRequestTimeout = 15 * time.Second
+)
+
+// Error is non-2xx response of gate
+type Error struct {
+	Path       string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: gate responded with status %d: %s", e.Path, e.StatusCode, e.Body)
+}
+
+// Client makes http calls to module endpoints through gate on behalf of application
+type Client struct {
+	baseUrl string
+	headers http.Header
+	http    *http.Client
+}
+
+// NewClient creates client for gate address returned by RunGateContainer
+// token is issued by system service, see utils/system.Client.ProvisionApplication
+func NewClient(addr structure.AddressConfiguration, token string) *Client {
+	headers := make(http.Header)
+	if token != "" {
+		headers.Set(ispUtils.ApplicationTokenHeader, token)
+	}
+	return &Client{
+		baseUrl: fmt.Sprintf("http://%s%s", addr.GetAddress(), DefaultPathPrefix),
+		headers: headers,
+		http:    &http.Client{Timeout: defaultRequestTimeout},
+	}
+}
+
+// WithHeader returns copy of client sending additional header, e.g. user token
+func (c *Client) WithHeader(key, value string) *Client {
+	headers := c.headers.Clone()
+	headers.Set(key, value)
+	return &Client{baseUrl: c.baseUrl, headers: headers, http: c.http}
+}
+
+// Invoke posts req as json to module endpoint and unmarshals response into resp, resp can be nil
+// req and resp are encoded the same way as isp modules do
+// returns *Error if gate responded with non-2xx status
+func (c *Client) Invoke(path string, req, resp interface{}) error {
+	var body []byte
+	if req != nil {
+		var err error
+		body, err = ispUtils.ConvertGoToBytes(req)
+		if err != nil {
+			return errors.Wrapf(err, "%s: marshal request", path)
+		}
+	}
+	status, respBody, err := c.Do(path, body)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return &Error{Path: path, StatusCode: status, Body: respBody}
+	}
+	if resp == nil || len(respBody) == 0 {
+		return nil
+	}
+	return errors.Wrapf(ispUtils.ConvertBytesToGo(respBody, resp), "%s: unmarshal response", path)
+}
+
+// Do posts raw json body to module endpoint and returns response status and body
+func (c *Client) Do(path string, body []byte) (int, []byte, error) {
+	url := c.baseUrl + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, path)
+	}
+	req.Header = c.headers.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, path)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, errors.Wrapf(err, "%s: read response", path)
+	}
+	return resp.StatusCode, respBody, nil
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/integration-system/isp-lib/v2/backend"
+	"github.com/integration-system/isp-lib/v2/structure"
+	ispUtils "github.com/integration-system/isp-lib/v2/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultInvokeTimeout = 15 * time.Second
+	// caller id used by config-service and other modules for internal calls
+	DefaultApplicationId = -1
+)
+
+// Container is implemented by docker.ContainerContext
+type Container interface {
+	GetIPAddress() string
+}
+
+type Option func(c *Client)
+
+// WithApplicationId sets application id passed to module in x-application-identity header
+func WithApplicationId(id int) Option {
+	return func(c *Client) {
+		c.applicationId = id
+	}
+}
+
+// WithHeader adds metadata sent with every call, e.g. x-user-identity
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.md.Set(key, value)
+	}
+}
+
+// WithUserId sets user id passed to module in x-user-identity header
+func WithUserId(id int) Option {
+	return WithHeader(ispUtils.UserIdHeader, strconv.Itoa(id))
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries retries calls failed with Unavailable code, e.g. while module is starting
+func WithRetries(retries int, interval time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryInterval = interval
+	}
+}
+
+// Client invokes endpoints of isp module by method path
+type Client struct {
+	rx            *backend.RxGrpcClient
+	applicationId int
+	md            metadata.MD
+	timeout       time.Duration
+	retries       int
+	retryInterval time.Duration
+}
+
+// NewClient creates client for module grpc address reachable from the host
+func NewClient(addr structure.AddressConfiguration, opts ...Option) *Client {
+	rx := backend.NewRxGrpcClient(backend.WithDialOptions(grpc.WithInsecure()))
+	rx.ReceiveAddressList([]structure.AddressConfiguration{addr})
+	c := &Client{
+		rx:            rx,
+		applicationId: DefaultApplicationId,
+		md:            metadata.MD{},
+		timeout:       defaultInvokeTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewContainerClient creates client for module running in container, port is the module grpc port
+func NewContainerClient(container Container, port string, opts ...Option) *Client {
+	return NewClient(structure.AddressConfiguration{IP: container.GetIPAddress(), Port: port}, opts...)
+}
+
+// With returns client sharing connection with c and applied options, e.g. another application id
+func (c *Client) With(opts ...Option) *Client {
+	copied := *c
+	copied.md = c.md.Copy()
+	for _, opt := range opts {
+		opt(&copied)
+	}
+	return &copied
+}
+
+// Raw returns underlying grpc client
+func (c *Client) Raw() *backend.RxGrpcClient {
+	return c.rx
+}
+
+// Close closes connection shared by all clients created by With
+func (c *Client) Close() error {
+	return c.rx.Close()
+}
+
+// Invoke calls module endpoint and unmarshals response into resp, resp can be nil
+// req can be struct or map encoded the same way as isp modules do, or json as string, []byte or json.RawMessage
+// returns *Error if module responded with grpc status
+func (c *Client) Invoke(method string, req, resp interface{}) error {
+	switch body := req.(type) {
+	case string:
+		req = json.RawMessage(body)
+	case []byte:
+		req = json.RawMessage(body)
+	}
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = c.rx.Invoke(method, c.applicationId, req, resp,
+			backend.WithMetadata(c.md.Copy()),
+			backend.WithTimeout(c.timeout),
+		)
+		if status.Code(err) != codes.Unavailable || attempt >= c.retries {
+			break
+		}
+		time.Sleep(c.retryInterval)
+	}
+	return DecodeError(method, err)
+}
+
+// InvokeJSON calls module endpoint with json request and returns json response
+func (c *Client) InvokeJSON(method string, req string) (string, error) {
+	var resp json.RawMessage
+	if err := c.Invoke(method, req, &resp); err != nil {
+		return "", err
+	}
+	return string(resp), nil
+}
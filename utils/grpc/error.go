@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error is isp module error decoded from grpc status
+type Error struct {
+	Method  string
+	Code    codes.Code
+	Message string
+	// field violations of isp validation errors, field names are in lower camel case
+	Violations map[string]string
+	// other status details
+	Details []interface{}
+
+	status *status.Status
+}
+
+func (e *Error) Error() string {
+	b := strings.Builder{}
+	_, _ = fmt.Fprintf(&b, "%s: %s: %s", e.Method, e.Code, e.Message)
+	fields := make([]string, 0, len(e.Violations))
+	for field := range e.Violations {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		_, _ = fmt.Fprintf(&b, "; %s: %s", field, e.Violations[field])
+	}
+	for _, detail := range e.Details {
+		_, _ = fmt.Fprintf(&b, "; %v", detail)
+	}
+	return b.String()
+}
+
+// GRPCStatus allows to use status.Code and status.Convert on decoded error
+func (e *Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// DecodeError converts grpc status error returned by isp module into *Error
+// errors without grpc status are returned as is
+func DecodeError(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	result := &Error{
+		Method:  method,
+		Code:    st.Code(),
+		Message: st.Message(),
+		status:  st,
+	}
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			result.Details = append(result.Details, detail)
+			continue
+		}
+		if result.Violations == nil {
+			result.Violations = make(map[string]string)
+		}
+		for _, violation := range badRequest.FieldViolations {
+			result.Violations[violation.Field] = violation.Description
+		}
+	}
+	return result
+}
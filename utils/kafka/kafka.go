@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+// Wait waits until one of brokers responds with cluster metadata
+func Wait(brokers []string, timeout time.Duration) (*kafka.Conn, error) {
+	conn, err := utils.AwaitConnection(func() (interface{}, error) {
+		var lastErr error
+		for _, broker := range brokers {
+			c, err := kafka.Dial("tcp", broker)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if _, err := c.Brokers(); err != nil {
+				_ = c.Close()
+				lastErr = err
+				continue
+			}
+			return c, nil
+		}
+		return nil, lastErr
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*kafka.Conn), nil
+}
+
+// create topic with replication factor 1 through controller broker
+func CreateTopic(conn *kafka.Conn, topic string, partitions int) error {
+	controller, err := conn.Controller()
+	if err != nil {
+		return errors.Wrap(err, "get controller")
+	}
+	controllerConn, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return errors.Wrap(err, "dial controller")
+	}
+	defer controllerConn.Close()
+
+	return controllerConn.CreateTopics(kafka.TopicConfig{
+		Topic:             topic,
+		NumPartitions:     partitions,
+		ReplicationFactor: 1,
+	})
+}
+
+// synchronously write messages into topic
+func Produce(brokers []string, topic string, messages ...kafka.Message) error {
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer w.Close()
+	return w.WriteMessages(context.Background(), messages...)
+}
+
+// read count messages from all partitions of topic starting from the first offset
+// returns messages read before timeout with error if less than count messages were read
+func Consume(brokers []string, topic string, count int, timeout time.Duration) ([]kafka.Message, error) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		GroupID:     fmt.Sprintf("isp-test-%d", time.Now().UnixNano()),
+		StartOffset: kafka.FirstOffset,
+		MaxWait:     100 * time.Millisecond,
+	})
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	messages := make([]kafka.Message, 0, count)
+	for len(messages) < count {
+		msg, err := r.ReadMessage(ctx)
+		if err != nil {
+			return messages, errors.Wrapf(err, "read %d of %d messages from %s", len(messages), count, topic)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
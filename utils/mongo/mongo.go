@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/integration-system/isp-lib/v2/structure"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// returns connection uri, user is authenticated against admin database
+func URI(cfg structure.DBConfiguration) string {
+	u := url.URL{
+		Scheme:   "mongodb",
+		Host:     fmt.Sprintf("%s:%s", cfg.Address, cfg.Port),
+		Path:     "/" + cfg.Database,
+		RawQuery: "authSource=admin",
+	}
+	if cfg.Username != "" {
+		u.User = url.UserPassword(cfg.Username, cfg.Password)
+	}
+	return u.String()
+}
+
+func Wait(cfg structure.DBConfiguration, timeout time.Duration) (*mongo.Client, error) {
+	client, err := utils.AwaitConnection(func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		c, err := mongo.Connect(ctx, options.Client().ApplyURI(URI(cfg)))
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Ping(ctx, nil); err != nil {
+			_ = c.Disconnect(context.Background())
+			return nil, err
+		}
+		return c, nil
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return client.(*mongo.Client), nil
+}
+
+// mongo creates database lazily, so database is created with specified collections
+func CreateDatabase(client *mongo.Client, database string, collections ...string) error {
+	db := client.Database(database)
+	for _, collection := range collections {
+		if err := db.CreateCollection(context.Background(), collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func LoadFixtures(client *mongo.Client, database, collection string, documents []interface{}) error {
+	if len(documents) == 0 {
+		return nil
+	}
+	_, err := client.Database(database).Collection(collection).InsertMany(context.Background(), documents)
+	return err
+}
+
+// load documents from file with array in mongo extended JSON format
+func LoadJSONFixture(client *mongo.Client, database, collection, filePath string) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var documents []interface{}
+	if err := bson.UnmarshalExtJSON(data, false, &documents); err != nil {
+		return err
+	}
+	return LoadFixtures(client, database, collection, documents)
+}
+
+// decode all documents matching filter into resultsPtr, which must be pointer to slice
+func Find(client *mongo.Client, database, collection string, filter interface{}, resultsPtr interface{}) error {
+	if filter == nil {
+		filter = bson.D{}
+	}
+	cursor, err := client.Database(database).Collection(collection).Find(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	return cursor.All(context.Background(), resultsPtr)
+}
+
+func Count(client *mongo.Client, database, collection string, filter interface{}) (int64, error) {
+	if filter == nil {
+		filter = bson.D{}
+	}
+	return client.Database(database).Collection(collection).CountDocuments(context.Background(), filter)
+}
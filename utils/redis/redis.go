@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/integration-system/isp-lib/v2/structure"
+	"github.com/stretchr/testify/assert"
+)
+
+func Wait(redisCfg structure.RedisConfiguration, timeout time.Duration) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisCfg.Address.GetAddress(),
+		Username: redisCfg.Username,
+		Password: redisCfg.Password,
+		DB:       redisCfg.DefaultDB,
+	})
+	_, err := utils.AwaitConnection(func() (interface{}, error) {
+		return nil, client.Ping(context.Background()).Err()
+	}, timeout)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// remove all keys from all databases
+func FlushAll(client *redis.Client) error {
+	return client.FlushAll(context.Background()).Err()
+}
+
+func AssertKeyExists(client *redis.Client, key string, assert *assert.Assertions) bool {
+	count, err := client.Exists(context.Background(), key).Result()
+	if !assert.NoError(err) {
+		return false
+	}
+	return assert.EqualValues(1, count, "key %s does not exist", key)
+}
+
+func AssertKeyNotExists(client *redis.Client, key string, assert *assert.Assertions) bool {
+	count, err := client.Exists(context.Background(), key).Result()
+	if !assert.NoError(err) {
+		return false
+	}
+	return assert.EqualValues(0, count, "key %s exists", key)
+}
+
+func AssertKeyValue(client *redis.Client, key string, expected string, assert *assert.Assertions) bool {
+	value, err := client.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return assert.Fail("key does not exist", key)
+	}
+	if !assert.NoError(err) {
+		return false
+	}
+	return assert.Equal(expected, value, "key %s", key)
+}
+
+// check that key has ttl within [min, max]
+func AssertKeyTTL(client *redis.Client, key string, min, max time.Duration, assert *assert.Assertions) bool {
+	ttl, err := client.TTL(context.Background(), key).Result()
+	if !assert.NoError(err) {
+		return false
+	}
+	if ttl < 0 {
+		return assert.Fail("key has no ttl or does not exist", key)
+	}
+	return assert.True(ttl >= min && ttl <= max, "key %s ttl %s is not within [%s, %s]", key, ttl, min, max)
+}
+
+// check that key exists and has no ttl
+func AssertKeyPersistent(client *redis.Client, key string, assert *assert.Assertions) bool {
+	ttl, err := client.TTL(context.Background(), key).Result()
+	if !assert.NoError(err) {
+		return false
+	}
+	return assert.Equal(time.Duration(-1), ttl, "key %s is not persistent or does not exist", key)
+}
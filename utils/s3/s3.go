@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/integration-system/isp-lib-test/ctx"
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+// Wait waits until object storage responds to list buckets request
+func Wait(cfg ctx.ObjectStorageConfiguration, timeout time.Duration) (*minio.Client, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = utils.AwaitConnection(func() (interface{}, error) {
+		return client.ListBuckets(context.Background())
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// create bucket if not exists
+func CreateBucket(client *minio.Client, bucket string) error {
+	exists, err := client.BucketExists(context.Background(), bucket)
+	if err != nil || exists {
+		return err
+	}
+	return client.MakeBucket(context.Background(), bucket, minio.MakeBucketOptions{})
+}
+
+func UploadFixture(client *minio.Client, bucket, key string, data []byte, opts minio.PutObjectOptions) error {
+	_, err := client.PutObject(context.Background(), bucket, key, bytes.NewReader(data), int64(len(data)), opts)
+	return err
+}
+
+func UploadFile(client *minio.Client, bucket, key, filePath string, opts minio.PutObjectOptions) error {
+	_, err := client.FPutObject(context.Background(), bucket, key, filePath, opts)
+	return err
+}
+
+// list all objects with prefix recursively
+func ListObjects(client *minio.Client, bucket, prefix string) ([]minio.ObjectInfo, error) {
+	objects := make([]minio.ObjectInfo, 0)
+	for obj := range client.ListObjects(context.Background(), bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+func Download(client *minio.Client, bucket, key string) ([]byte, error) {
+	obj, err := client.GetObject(context.Background(), bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return ioutil.ReadAll(obj)
+}
+
+func AssertObjectExists(client *minio.Client, bucket, key string, assert *assert.Assertions) bool {
+	_, err := client.StatObject(context.Background(), bucket, key, minio.StatObjectOptions{})
+	return assert.NoError(err, "object %s/%s", bucket, key)
+}
+
+func AssertObjectContent(client *minio.Client, bucket, key string, expected []byte, assert *assert.Assertions) bool {
+	data, err := Download(client, bucket, key)
+	if !assert.NoError(err, "object %s/%s", bucket, key) {
+		return false
+	}
+	return assert.Equal(expected, data, "object %s/%s", bucket, key)
+}
+
+// check content type, if not empty, and user metadata, metadata keys are case insensitive
+func AssertObjectMetadata(client *minio.Client, bucket, key string, contentType string, metadata map[string]string, assert *assert.Assertions) bool {
+	info, err := client.StatObject(context.Background(), bucket, key, minio.StatObjectOptions{})
+	if !assert.NoError(err, "object %s/%s", bucket, key) {
+		return false
+	}
+	ok := true
+	if contentType != "" {
+		ok = assert.Equal(contentType, info.ContentType, "object %s/%s content type", bucket, key) && ok
+	}
+	actual := make(map[string]string, len(info.UserMetadata))
+	for k, v := range info.UserMetadata {
+		actual[strings.ToLower(k)] = v
+	}
+	for k, v := range metadata {
+		ok = assert.Equal(v, actual[strings.ToLower(k)], "object %s/%s metadata %s", bucket, key, k) && ok
+	}
+	return ok
+}
@@ -0,0 +1,170 @@
+package system
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/integration-system/isp-lib-test/utils"
+	"github.com/integration-system/isp-lib/v2/backend"
+	"github.com/integration-system/isp-lib/v2/structure"
+	ispUtils "github.com/integration-system/isp-lib/v2/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	getApplicationsCommand   = "system/application/get_applications"
+	upsertDomainCommand      = "system/domain/create_update_domain"
+	upsertServiceCommand     = "system/service/create_update_service"
+	upsertApplicationCommand = "system/application/create_update_application"
+	createTokenCommand       = "system/token/create_token"
+	setAccessListCommand     = "system/access_list/set_list"
+
+	// system created by system service migrations
+	DefaultSystemId = 1
+	// application type allowed to call module endpoints through gate
+	SystemApplicationType = "SYSTEM"
+
+	adminCallerId             = -1
+	defaultAdminInvokeTimeout = 10 * time.Second
+)
+
+// Client is typed client of system service admin api
+type Client struct {
+	rx       *backend.RxGrpcClient
+	systemId int32
+}
+
+func NewClient(rx *backend.RxGrpcClient) *Client {
+	return &Client{rx: rx, systemId: DefaultSystemId}
+}
+
+// Connect waits until system service answers admin requests and returns typed client
+// addr is the grpc address returned by RunSystemServiceContainer
+func Connect(addr structure.AddressConfiguration, timeout time.Duration) (*Client, error) {
+	rx := backend.NewRxGrpcClient(backend.WithDialOptions(grpc.WithInsecure()))
+	rx.ReceiveAddressList([]structure.AddressConfiguration{addr})
+	c := NewClient(rx)
+	_, err := utils.AwaitConnection(func() (interface{}, error) {
+		return c.Applications()
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Raw returns underlying grpc client
+func (c *Client) Raw() *backend.RxGrpcClient {
+	return c.rx
+}
+
+func (c *Client) invoke(method string, req, resp interface{}) error {
+	md := metadata.Pairs(ispUtils.SystemIdHeader, strconv.Itoa(int(c.systemId)))
+	err := c.rx.Invoke(method, adminCallerId, req, resp,
+		backend.WithTimeout(defaultAdminInvokeTimeout),
+		backend.WithMetadata(md),
+	)
+	return errors.Wrap(err, method)
+}
+
+// Applications returns all applications of the default system
+func (c *Client) Applications() ([]Application, error) {
+	var apps []Application
+	err := c.invoke(getApplicationsCommand, nil, &apps)
+	return apps, err
+}
+
+// UpsertDomain creates domain if id is empty or updates existing one
+func (c *Client) UpsertDomain(domain Domain) (*Domain, error) {
+	if domain.SystemId == 0 {
+		domain.SystemId = c.systemId
+	}
+	result := new(Domain)
+	if err := c.invoke(upsertDomainCommand, domain, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpsertService creates service if id is empty or updates existing one
+func (c *Client) UpsertService(service Service) (*Service, error) {
+	result := new(Service)
+	if err := c.invoke(upsertServiceCommand, service, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpsertApplication creates application if id is empty or updates existing one
+func (c *Client) UpsertApplication(app Application) (*Application, error) {
+	if app.Type == "" {
+		app.Type = SystemApplicationType
+	}
+	result := new(ApplicationWithTokens)
+	if err := c.invoke(upsertApplicationCommand, app, result); err != nil {
+		return nil, err
+	}
+	return &result.App, nil
+}
+
+// CreateToken issues application token, zero expireTime means token never expires
+func (c *Client) CreateToken(appId int32, expireTime time.Duration) (*Token, error) {
+	req := createTokenRequest{AppId: appId, ExpireTimeMs: -1}
+	if expireTime > 0 {
+		req.ExpireTimeMs = expireTime.Milliseconds()
+	}
+	result := new(ApplicationWithTokens)
+	if err := c.invoke(createTokenCommand, req, result); err != nil {
+		return nil, err
+	}
+	if len(result.Tokens) == 0 {
+		return nil, errors.Errorf("%s: no tokens returned for application %d", createTokenCommand, appId)
+	}
+	token := result.Tokens[len(result.Tokens)-1]
+	return &token, nil
+}
+
+// SetAccessList allows or denies application to call endpoints through gate
+func (c *Client) SetAccessList(appId int32, methods ...MethodAccess) error {
+	var resp interface{}
+	return c.invoke(setAccessListCommand, setAccessListRequest{AppId: appId, Methods: methods}, &resp)
+}
+
+// ProvisionApplication creates domain, service, application and token named after name in the default system
+// application is allowed to call listed endpoints, access list is left untouched if methods are empty
+func (c *Client) ProvisionApplication(name string, methods ...string) (*Credentials, error) {
+	domain, err := c.UpsertDomain(Domain{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	service, err := c.UpsertService(Service{Name: name, DomainId: domain.Id})
+	if err != nil {
+		return nil, err
+	}
+	app, err := c.UpsertApplication(Application{Name: name, ServiceId: service.Id})
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) > 0 {
+		access := make([]MethodAccess, len(methods))
+		for i, method := range methods {
+			access[i] = MethodAccess{Method: method, Value: true}
+		}
+		if err := c.SetAccessList(app.Id, access...); err != nil {
+			return nil, err
+		}
+	}
+	token, err := c.CreateToken(app.Id, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{
+		SystemId:      domain.SystemId,
+		DomainId:      domain.Id,
+		ServiceId:     service.Id,
+		ApplicationId: app.Id,
+		Token:         token.Token,
+	}, nil
+}
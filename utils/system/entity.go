@@ -0,0 +1,70 @@
+package system
+
+import "time"
+
+// entities of system service admin api
+
+type Domain struct {
+	Id          int32
+	Name        string
+	Description string
+	SystemId    int32
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type Service struct {
+	Id          int32
+	Name        string
+	Description string
+	DomainId    int32
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type Application struct {
+	Id          int32
+	Name        string
+	Description string
+	ServiceId   int32
+	Type        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type Token struct {
+	Token      string
+	AppId      int32
+	ExpireTime int64
+	CreatedAt  time.Time
+}
+
+type ApplicationWithTokens struct {
+	App    Application
+	Tokens []Token
+}
+
+// MethodAccess allows or denies application to call endpoint
+type MethodAccess struct {
+	Method string
+	Value  bool
+}
+
+// Credentials identify application provisioned for test
+type Credentials struct {
+	SystemId      int32
+	DomainId      int32
+	ServiceId     int32
+	ApplicationId int32
+	Token         string
+}
+
+type createTokenRequest struct {
+	AppId        int32 `json:"appId"`
+	ExpireTimeMs int64 `json:"expireTimeMs"`
+}
+
+type setAccessListRequest struct {
+	AppId   int32          `json:"appId"`
+	Methods []MethodAccess `json:"methods"`
+}